@@ -0,0 +1,294 @@
+package monitor
+
+import (
+	"context"
+	"hash/maphash"
+	"maps"
+	"runtime"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// DropPolicy 异步模式下队列已满时的处理方式
+type DropPolicy string
+
+const (
+	DropNewest DropPolicy = "drop_newest" // 丢弃本次打点, 默认值
+	DropOldest DropPolicy = "drop_oldest" // 丢弃队列中最早的打点
+	DropBlock  DropPolicy = "block"       // 阻塞等待队列有空位, 不丢弃
+)
+
+// AsyncOpt 异步模式的选项
+type AsyncOpt func(*asyncQueue)
+
+// AsyncShards 设置分片数, 每个分片有独立的队列及后台 goroutine, 同一个指标总是进入同一个分片
+// 默认值是 runtime.GOMAXPROCS(0)
+func AsyncShards(n int) AsyncOpt {
+	return func(q *asyncQueue) {
+		q.shardCount = n
+	}
+}
+
+// AsyncQueueSize 设置每个分片的队列长度, 默认值是 4096
+func AsyncQueueSize(n int) AsyncOpt {
+	return func(q *asyncQueue) {
+		q.queueSize = n
+	}
+}
+
+// AsyncBatchSize 设置后台 goroutine 每批最多处理的打点数, 默认值是 256
+func AsyncBatchSize(n int) AsyncOpt {
+	return func(q *asyncQueue) {
+		q.batchSize = n
+	}
+}
+
+// AsyncDropPolicy 设置队列已满时的处理方式, 默认值是 DropNewest
+func AsyncDropPolicy(policy DropPolicy) AsyncOpt {
+	return func(q *asyncQueue) {
+		q.policy = policy
+	}
+}
+
+// WithAsync 开启异步模式: 打点 API 只将调用参数放入队列即返回, 由后台 goroutine 拼接指标名, 合并标签后批量写入各个 Sink
+//
+// 后台 goroutine 会合并同一批次中同一 client 相同指标及标签的打点: counter 累加, gauge 按顺序合并为一次 Store 或 Add;
+// histogram 及 summary 逐个写入. 队列已满时按 AsyncDropPolicy 处理,
+// 丢弃的打点记录到内部异常指标中(kind 为 async_dropped).
+// 测试中读取指标前, 需要先调用 Flush; Close 时会写入队列中剩余的打点, 关闭后的打点同步写入.
+// 派生的子 client 共享队列. 异步模式下 Catalog 中没有调用位置, Sink 收到的 ctx 可能已经取消.
+//
+//	c := monitor.NewClient(monitor.WithAsync(monitor.AsyncQueueSize(1<<14), monitor.AsyncDropPolicy(monitor.DropOldest)))
+//	defer c.Close()
+func WithAsync(opts ...AsyncOpt) Opt {
+	return func(c *client) {
+		c.starters = append(c.starters, func(c *client) {
+			q := &asyncQueue{
+				shardCount: runtime.GOMAXPROCS(0),
+				queueSize:  4096,
+				batchSize:  256,
+				policy:     DropNewest,
+				seed:       maphash.MakeSeed(),
+				done:       c.life.done,
+			}
+			for _, opt := range opts {
+				opt(q)
+			}
+			q.shardCount, q.queueSize, q.batchSize = max(q.shardCount, 1), max(q.queueSize, 1), max(q.batchSize, 1)
+			q.shards = make([]*asyncShard, q.shardCount)
+			for i := range q.shards {
+				shard := &asyncShard{
+					items: make(chan asyncItem, q.queueSize),
+					flush: make(chan chan struct{}),
+				}
+				q.shards[i] = shard
+				c.life.goBackground(func(done <-chan struct{}) { q.run(shard, done) })
+			}
+			c.life.addCloser(func() error {
+				q.closed.Store(true) // 之后的打点同步写入
+				for _, shard := range q.shards {
+					for shard.inflight.Load() > 0 {
+						runtime.Gosched() // 等待正在入队的打点完成
+					}
+					q.drain(shard) // 关闭过程中放入队列的打点
+				}
+				return nil
+			})
+			c.async = q
+		})
+	}
+}
+
+// asyncQueue 异步模式的分片队列
+type asyncQueue struct {
+	shardCount int
+	queueSize  int
+	batchSize  int
+	policy     DropPolicy
+	seed       maphash.Seed
+	shards     []*asyncShard
+	done       <-chan struct{}
+	closed     atomic.Bool // 关闭后不再有打点进入队列
+}
+
+type asyncShard struct {
+	items    chan asyncItem
+	flush    chan chan struct{} // Flush 请求, 处理完队列中已有的打点后关闭
+	inflight atomic.Int64       // 正在入队的打点数, 关闭时等待其归零, 使入队时不需要加锁
+}
+
+// asyncItem 队列中的一次打点
+type asyncItem struct {
+	c   *client
+	ctx context.Context
+	cl  call
+}
+
+// enqueue 将打点调用放入队列, 返回 false 表示已关闭, 需要同步写入
+func (q *asyncQueue) enqueue(c *client, ctx context.Context, cl call) bool {
+	shard := q.shards[maphash.String(q.seed, cl.name)%uint64(len(q.shards))]
+	// 先增加计数再检查关闭标记: 关闭时先设置标记再等待计数归零, 两者之间不会有打点遗漏在队列中
+	shard.inflight.Add(1)
+	defer shard.inflight.Add(-1)
+	if q.closed.Load() {
+		return false
+	}
+	cl.kvs = slices.Clone(cl.kvs) // 调用方可能复用 kvs
+	item := asyncItem{c: c, ctx: ctx, cl: cl}
+	switch q.policy {
+	case DropBlock:
+		select {
+		case shard.items <- item:
+		case <-q.done:
+			return false // 后台 goroutine 已退出, 不能再等待
+		}
+	case DropOldest:
+		for {
+			select {
+			case shard.items <- item:
+				return true
+			default:
+			}
+			select {
+			case old := <-shard.items:
+				old.drop()
+			default:
+			}
+		}
+	default:
+		select {
+		case shard.items <- item:
+		default:
+			item.drop()
+		}
+	}
+	return true
+}
+
+// drop 记录丢弃的打点
+func (item asyncItem) drop() {
+	item.c.recordErr(item.c.prometheusOpt(item.cl.name, item.cl.desc, item.cl.names).Name, "async_dropped")
+}
+
+// run 后台 goroutine, 批量处理一个分片的打点
+func (q *asyncQueue) run(shard *asyncShard, done <-chan struct{}) {
+	batch := make([]asyncItem, 0, q.batchSize)
+	for {
+		select {
+		case item := <-shard.items:
+			batch = append(batch[:0], item)
+		loop:
+			for len(batch) < q.batchSize {
+				select {
+				case item := <-shard.items:
+					batch = append(batch, item)
+				default:
+					break loop
+				}
+			}
+			apply(batch)
+		case ch := <-shard.flush:
+			q.drain(shard)
+			close(ch)
+		case <-done:
+			q.drain(shard)
+			return
+		}
+	}
+}
+
+// drain 处理分片队列中当前所有的打点
+func (q *asyncQueue) drain(shard *asyncShard) {
+	batch := make([]asyncItem, 0, q.batchSize)
+	for {
+		select {
+		case item := <-shard.items:
+			if batch = append(batch, item); len(batch) == q.batchSize {
+				apply(batch)
+				batch = batch[:0]
+			}
+		default:
+			apply(batch)
+			return
+		}
+	}
+}
+
+// flush 等待所有分片处理完调用前已放入队列的打点
+func (q *asyncQueue) flush() {
+	var waits []chan struct{}
+	for _, shard := range q.shards {
+		ch := make(chan struct{})
+		select {
+		case shard.flush <- ch:
+			waits = append(waits, ch)
+		case <-q.done:
+			return
+		}
+	}
+	for _, ch := range waits {
+		<-ch
+	}
+}
+
+// asyncEvent 后台 goroutine 中整理好的打点事件
+type asyncEvent struct {
+	c   *client
+	ctx context.Context
+	e   Event
+}
+
+// apply 整理一批打点调用, 合并其中相同指标及标签的 counter/gauge 后写入
+func apply(batch []asyncItem) {
+	type key struct {
+		c                         *client
+		kind                      Kind
+		name, help, labels, alias string
+	}
+	index := map[key]int{}
+	merged := make([]asyncEvent, 0, len(batch))
+	for _, item := range batch {
+		e := item.c.event(item.cl)
+		e.async = true
+		if e.Kind != KindCounter && e.Kind != KindGauge {
+			merged = append(merged, asyncEvent{item.c, item.ctx, e})
+			continue
+		}
+		k := key{item.c, e.Kind, e.Name, e.Help, labelKey(e.Labels), e.alias}
+		i, ok := index[k]
+		if !ok {
+			index[k] = len(merged)
+			merged = append(merged, asyncEvent{item.c, item.ctx, e})
+			continue
+		}
+		m := &merged[i].e
+		if e.Kind == KindCounter || e.Add {
+			m.Value += e.Value // Store 之后的 Add 合并为 Store 累加后的值
+		} else {
+			m.Value, m.Add = e.Value, false // Store 覆盖之前的 Store/Add
+		}
+	}
+	for _, item := range merged {
+		item.c.dispatch(item.ctx, item.e)
+	}
+}
+
+// labelKey 将标签转换为可比较的字符串
+func labelKey(labels map[string]string) string {
+	var sb strings.Builder
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		sb.WriteString(k)
+		sb.WriteByte(0xff)
+		sb.WriteString(labels[k])
+		sb.WriteByte(0xfe)
+	}
+	return sb.String()
+}
+
+// Flush 异步模式下等待调用前的打点全部写入各个 Sink, 非异步模式下直接返回
+func (c *client) Flush() {
+	if c.async != nil {
+		c.async.flush()
+	}
+}
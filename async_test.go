@@ -0,0 +1,167 @@
+package monitor_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"code.gopub.tech/commons/assert"
+	"code.gopub.tech/monitor"
+)
+
+func TestAsync(t *testing.T) {
+	var events []monitor.Event
+	var mu sync.Mutex
+	entered, release := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	c := monitor.NewClient(
+		monitor.WithAsync(monitor.AsyncShards(1), monitor.AsyncBatchSize(4096)),
+		monitor.WithSinks(monitor.PrometheusSink, monitor.SinkFunc(func(_ context.Context, e monitor.Event) {
+			mu.Lock()
+			events = append(events, e)
+			mu.Unlock()
+			once.Do(func() {
+				close(entered)
+				<-release // 阻塞后台 goroutine, 使之后的打点进入同一批次
+			})
+		})))
+	defer c.Close()
+	c.Record(ctx, "start", "开始")
+	<-entered
+
+	db := c.Sub("db") // 合并按 client 区分, 每次 Sub 都会派生新的子 client
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.Record(ctx, "req", "请求数", "k", "v")
+				db.Store(ctx, "conns", "连接数", 3)
+			}
+		}()
+	}
+	wg.Wait()
+	c.Add(ctx, "queue", "队列长度", 1)
+	c.Store(ctx, "queue", "队列长度", 5)
+	c.Add(ctx, "queue", "队列长度", 2)
+	close(release)
+	c.Flush()
+
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	t.Log(body)
+	assert.True(t, strings.Contains(body, "\ncounter:req{k=\"v\"} 1000\n"))
+	assert.True(t, strings.Contains(body, "\ndb:gauge:conns 3\n"))
+	assert.True(t, strings.Contains(body, "\ngauge:queue 7\n"))
+	mu.Lock()
+	// 同一批次中相同指标及标签的打点合并为一个事件
+	var names []string
+	for _, e := range events {
+		names = append(names, e.Name)
+	}
+	assert.DeepEqual(t, names, []string{"counter:start", "counter:req", "db:gauge:conns", "gauge:queue"})
+	assert.True(t, events[1].Value == 1000, events[1])
+	mu.Unlock()
+
+	// 后台 goroutine 中取不到调用位置
+	assert.True(t, c.Catalog()[0].CallSite == "", c.Catalog())
+}
+
+func TestAsyncDropPolicy(t *testing.T) {
+	record := func(policy monitor.DropPolicy) string {
+		entered, release := make(chan struct{}), make(chan struct{})
+		var once sync.Once
+		c := monitor.NewClient(
+			monitor.WithAsync(monitor.AsyncShards(1), monitor.AsyncQueueSize(1), monitor.AsyncDropPolicy(policy)),
+			monitor.WithSinks(monitor.SinkFunc(func(context.Context, monitor.Event) {
+				once.Do(func() {
+					close(entered)
+					<-release // 阻塞后台 goroutine, 使队列填满
+				})
+			}), monitor.PrometheusSink),
+		)
+		defer c.Close()
+		c.RecordN(ctx, "jobs", "任务数", 1)
+		<-entered
+		c.RecordN(ctx, "jobs", "任务数", 10)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			c.RecordN(ctx, "jobs", "任务数", 100) // 队列已满
+		}()
+		if policy != monitor.DropBlock {
+			<-done
+		}
+		close(release)
+		<-done
+		c.Flush()
+		w := httptest.NewRecorder()
+		c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		t.Log(w.Body.String())
+		return w.Body.String()
+	}
+	dropped := `internal_monitor_error{kind="async_dropped",name="counter:jobs"} 1`
+
+	body := record(monitor.DropNewest)
+	assert.True(t, strings.Contains(body, "\ncounter:jobs 11\n"))
+	assert.True(t, strings.Contains(body, dropped))
+
+	body = record(monitor.DropOldest)
+	assert.True(t, strings.Contains(body, "\ncounter:jobs 101\n"))
+	assert.True(t, strings.Contains(body, dropped))
+
+	body = record(monitor.DropBlock)
+	assert.True(t, strings.Contains(body, "\ncounter:jobs 111\n"))
+	assert.True(t, !strings.Contains(body, "async_dropped"))
+}
+
+func TestAsyncClose(t *testing.T) {
+	c := monitor.NewClient(monitor.WithAsync())
+	c.Record(ctx, "req", "请求数")
+	c.Close()                   // 写入队列中剩余的打点
+	c.Record(ctx, "req", "请求数") // 关闭后同步写入
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.True(t, strings.Contains(w.Body.String(), "\ncounter:req 2\n"), w.Body.String())
+}
+
+func TestAsyncCloseConcurrent(t *testing.T) {
+	for _, policy := range []monitor.DropPolicy{monitor.DropBlock, monitor.DropOldest} {
+		c := monitor.NewClient(monitor.WithAsync(monitor.AsyncQueueSize(4), monitor.AsyncDropPolicy(policy)))
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 500; j++ {
+					c.Record(ctx, "req", "请求数")
+				}
+			}()
+		}
+		c.Close() // 关闭过程中的打点不会丢失
+		wg.Wait()
+		w := httptest.NewRecorder()
+		c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		body := w.Body.String()
+		if policy == monitor.DropBlock {
+			assert.True(t, strings.Contains(body, "\ncounter:req 4000\n"), body)
+		}
+		// DropOldest 时被丢弃的打点都记录在内部异常指标中
+		families, err := c.Registry().Gather()
+		assert.True(t, err == nil, err)
+		var total float64
+		for _, mf := range families {
+			for _, m := range mf.GetMetric() {
+				switch mf.GetName() {
+				case "counter:req", "counter:internal_monitor_error":
+					total += m.GetCounter().GetValue()
+				}
+			}
+		}
+		assert.True(t, total == 4000, policy, total)
+	}
+}
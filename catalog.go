@@ -0,0 +1,178 @@
+package monitor
+
+import (
+	"cmp"
+	"encoding/json"
+	"html/template"
+	"maps"
+	"net/http"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CatalogEntry 一个通过 client 的 API 注册的指标
+type CatalogEntry struct {
+	Metric     string             `json:"metric"`               // 调用时传入的指标名
+	Name       string             `json:"name"`                 // 完整指标名
+	Kind       Kind               `json:"kind"`                 // 指标类型
+	Help       string             `json:"help"`                 // 指标说明
+	Labels     []string           `json:"labels"`               // 标签名(不含常量标签)
+	Buckets    []float64          `json:"buckets,omitempty"`    // 仅 histogram 类型有值
+	Objectives map[string]float64 `json:"objectives,omitempty"` // 仅 summary 类型有值, 分位数 => 允许误差
+	CallSite   string             `json:"call_site"`            // 首次注册时的调用位置 file:line, 异步模式下为空
+	Series     int                `json:"series"`               // 当前的时间序列数
+	LastWrite  time.Time          `json:"last_write"`           // 最后一次打点的时间
+}
+
+// metricInfo 记录已写入 registry 的指标, 用于 Catalog 及 Reconfigure
+type metricInfo struct {
+	metric    string
+	kind      Kind
+	help      string
+	labels    []string
+	callSite  string
+	defaulted bool                    // 是否使用了默认的分布/分位数
+	owner     *atomic.Pointer[tuning] // 首次写入的 client 的配置, Reconfigure 只处理自己的指标
+	lastWrite atomic.Int64
+	conflicts sync.Map // 已输出过的不一致, 参见 checkSchema
+}
+
+// noteWrite 记录指标 name 的一次写入, 首次写入时记录调用位置
+func (c *client) noteWrite(e Event, name string) {
+	info, ok := c.seen.Load(name)
+	if !ok {
+		info, _ = c.seen.LoadOrStore(name, &metricInfo{
+			metric:    e.Metric,
+			kind:      e.Kind,
+			help:      e.Help,
+			labels:    e.labelNames(),
+			callSite:  e.site(),
+			defaulted: e.tuning != nil,
+			owner:     c.tuning,
+		})
+	}
+	info.lastWrite.Store(time.Now().UnixNano())
+}
+
+// site 返回打点的调用位置
+func (e Event) site() string {
+	if e.async {
+		return ""
+	}
+	return callSite()
+}
+
+// pkgPrefix 本包函数名的前缀, 如 code.gopub.tech/monitor.
+var pkgPrefix = func() string {
+	pc, _, _, _ := runtime.Caller(0)
+	name := runtime.FuncForPC(pc).Name()
+	slash := strings.LastIndex(name, "/")
+	return name[:slash+1+strings.Index(name[slash+1:], ".")+1]
+}()
+
+// callSite 返回调用栈上第一个不在本包内的位置
+func callSite() string {
+	var pcs [32]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs[:])])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, pkgPrefix) {
+			return f.File + ":" + strconv.Itoa(f.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// Catalog 返回通过 client 的打点 API (Record, Cost 等) 注册的所有指标, 按完整指标名排序
+// 用于审查各服务的打点情况. 共享指标缓存的 client (如 Sub 派生的子 client) 返回相同的结果.
+func (c *client) Catalog() []CatalogEntry {
+	series := map[string]int{}
+	families, _ := c.registry.Gather() // 部分指标出错时仍然返回其他指标
+	for _, mf := range families {
+		series[mf.GetName()] = len(mf.GetMetric())
+	}
+	var entries []CatalogEntry
+	c.seen.Range(func(name string, info *metricInfo) bool {
+		e := CatalogEntry{
+			Metric:   info.metric,
+			Name:     name,
+			Kind:     info.kind,
+			Help:     info.help,
+			Labels:   info.labels,
+			CallSite: info.callSite,
+			Series:   series[name],
+		}
+		if ns := info.lastWrite.Load(); ns != 0 {
+			e.LastWrite = time.Unix(0, ns)
+		}
+		if v, ok := c.histogram.Load(name); ok {
+			e.Buckets = v.Val2.Buckets
+		}
+		if v, ok := c.summary.Load(name); ok && len(v.Val2.Objectives) > 0 {
+			e.Objectives = map[string]float64{}
+			for q, tolerance := range v.Val2.Objectives {
+				e.Objectives[strconv.FormatFloat(q, 'g', -1, 64)] = tolerance
+			}
+		}
+		entries = append(entries, e)
+		return true
+	})
+	slices.SortFunc(entries, func(a, b CatalogEntry) int { return cmp.Compare(a.Name, b.Name) })
+	return entries
+}
+
+// CatalogHandler 返回展示 Catalog 的 http.Handler
+// 请求参数 format=json 或请求头 Accept 包含 application/json 时返回 JSON, 否则返回 HTML 表格.
+//
+//	http.Handle("/metrics/catalog", c.CatalogHandler())
+func (c *client) CatalogHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entries := c.Catalog()
+		if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			enc.Encode(entries)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		catalogTemplate.Execute(w, entries)
+	})
+}
+
+var catalogTemplate = template.Must(template.New("catalog").Funcs(template.FuncMap{
+	"join": strings.Join,
+	"objectives": func(m map[string]float64) string {
+		var items []string
+		for _, q := range slices.Sorted(maps.Keys(m)) {
+			items = append(items, q+":"+strconv.FormatFloat(m[q], 'g', -1, 64))
+		}
+		return strings.Join(items, " ")
+	},
+	"time": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Metrics Catalog</title>
+<style>table{border-collapse:collapse}th,td{border:1px solid #ccc;padding:4px 8px;text-align:left;font-family:monospace}</style>
+</head>
+<body>
+<h1>Metrics Catalog ({{len .}})</h1>
+<table>
+<tr><th>metric</th><th>name</th><th>kind</th><th>help</th><th>labels</th><th>buckets / objectives</th><th>call site</th><th>series</th><th>last write</th></tr>
+{{range .}}<tr><td>{{.Metric}}</td><td>{{.Name}}</td><td>{{.Kind}}</td><td>{{.Help}}</td><td>{{join .Labels ", "}}</td><td>{{if .Buckets}}{{.Buckets}}{{else}}{{objectives .Objectives}}{{end}}</td><td>{{.CallSite}}</td><td>{{.Series}}</td><td>{{time .LastWrite}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,50 @@
+package monitor_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"code.gopub.tech/commons/assert"
+	"code.gopub.tech/monitor"
+)
+
+func TestCatalog(t *testing.T) {
+	start := time.Now()
+	c := monitor.NewClient(monitor.WithObjectives(map[float64]float64{0.5: 0.05}))
+	c.Record(ctx, "req", "请求数", "path", "/a")
+	c.Record(ctx, "req", "请求数", "path", "/b")
+	c.Sub("db").Cost(ctx, "query", "查询耗时", time.Millisecond)
+	c.Summary(ctx, "size", "大小", 1)
+
+	entries := c.Catalog()
+	assert.True(t, len(entries) == 3, entries)
+	req := entries[0]
+	assert.True(t, req.Name == "counter:req" && req.Metric == "req" && req.Kind == monitor.KindCounter, req)
+	assert.True(t, req.Help == "请求数" && req.Series == 2, req)
+	assert.DeepEqual(t, req.Labels, []string{"path"})
+	assert.True(t, strings.Contains(req.CallSite, "catalog_test.go:"), req.CallSite)
+	assert.True(t, !req.LastWrite.Before(start), req.LastWrite)
+
+	query := entries[1]
+	assert.True(t, query.Name == "db:timer:query_seconds" && query.Kind == monitor.KindHistogram, query)
+	assert.True(t, len(query.Buckets) == 11, query.Buckets)
+
+	size := entries[2]
+	assert.DeepEqual(t, size.Objectives, map[string]float64{"0.5": 0.05})
+
+	w := httptest.NewRecorder()
+	c.CatalogHandler().ServeHTTP(w, httptest.NewRequest("GET", "/catalog?format=json", nil))
+	var decoded []monitor.CatalogEntry
+	assert.True(t, json.Unmarshal(w.Body.Bytes(), &decoded) == nil, w.Body.String())
+	assert.True(t, len(decoded) == 3 && decoded[0].Name == "counter:req", decoded)
+
+	w = httptest.NewRecorder()
+	c.CatalogHandler().ServeHTTP(w, httptest.NewRequest("GET", "/catalog", nil))
+	body := w.Body.String()
+	assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "text/html"))
+	assert.True(t, strings.Contains(body, "<td>db:timer:query_seconds</td>"), body)
+	assert.True(t, strings.Contains(body, "0.5:0.05"), body)
+}
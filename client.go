@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"code.gopub.tech/commons/choose"
@@ -27,18 +29,46 @@ import (
 
 // client 是一个监控打点客户端
 type client struct {
-	namespace   string
-	subsystem   string
-	names       NameAppends
-	registry    *prometheus.Registry
-	constLabels map[string]string
-	logger      func(context.Context, string, ...any)
-	buckets     []float64
-	objectives  map[float64]float64
-	counter     *syncs.Map[string, *prometheus.CounterVec]
-	gauge       *syncs.Map[string, *prometheus.GaugeVec]
-	histogram   *syncs.Map[string, values.Tuple2[*prometheus.HistogramVec, prometheus.HistogramOpts]]
-	summary     *syncs.Map[string, values.Tuple2[*prometheus.SummaryVec, prometheus.SummaryOpts]]
+	namespace    string
+	subsystem    string
+	names        NameAppends // WithNameAppend 设置的前缀/后缀, 为空时使用命名方式的默认值
+	naming       NamingScheme
+	appends      NameAppends  // 实际使用的前缀/后缀
+	legacy       NameAppends  // NamingDual 时旧指标名使用的前缀/后缀
+	utf8Names    bool         // 指标名/标签名使用 UTF-8 原样输出
+	escaping     NameEscaping // utf8Names 时不支持 UTF-8 的抓取方使用的转义方式
+	timeUnit     time.Duration
+	async        *asyncQueue // 非 nil 时异步写入
+	registry     *prometheus.Registry
+	constLabels  map[string]string
+	logger       func(context.Context, string, ...any)
+	buckets      []float64
+	objectives   map[float64]float64
+	overrides    []metricOverride // 按指标名覆盖默认的分布/分位数
+	enabled      []string         // 非空时只记录这些指标
+	disabled     []string         // 不记录这些指标
+	tuning       *atomic.Pointer[tuning]
+	mismatch     MismatchStrategy
+	strictSchema bool
+	lint         *nameLinter // 非 nil 时检查指标命名
+	sinks        []Sink
+	classify     func(error) string
+	labels       map[string]string
+	errCounter   *prometheus.CounterVec
+	starters     []func(*client) // 构造完成后执行, 用于启动后台任务等
+	server       *ServerConfig   // 非 nil 时在后台启动独立指标服务, 参见 WithServer
+	life         *lifecycle
+	cacheMu      *sync.RWMutex                   // 写指标时持有读锁, Reconfigure 重建指标时持有写锁
+	replaceMu    *sync.RWMutex                   // MismatchReplace 时写 histogram/summary 持有读锁, 替换指标时持有写锁
+	replaced     map[string]time.Time            // MismatchReplace 最近一次替换指标的时间, 持有 replaceMu 写锁时读写
+	seen         *syncs.Map[string, *metricInfo] // 完整指标名 => 指标信息
+	escaped      *syncs.Map[string, string]      // 开启 UTF-8 指标名时, 按 fallback 转义后的指标名 => 完整指标名
+	escapes      *syncs.Map[string, string]      // 开启 UTF-8 指标名时, 指标名/标签名 => 按 fallback 转义后的名称
+	counter      *syncs.Map[string, *prometheus.CounterVec]
+	gauge        *syncs.Map[string, *prometheus.GaugeVec]
+	histogram    *syncs.Map[string, values.Tuple2[*prometheus.HistogramVec, prometheus.HistogramOpts]]
+	summary      *syncs.Map[string, values.Tuple2[*prometheus.SummaryVec, prometheus.SummaryOpts]]
+	trackers     *syncs.Map[string, *tracker]
 }
 
 // NameAppends 自定义 Counter/Gauge/Histogram/Summary 指标名称前缀/后缀
@@ -59,23 +89,87 @@ type NameAppend struct {
 
 // NewClient 新建监控打点客户端
 func NewClient(opts ...Opt) *client {
-	c := &client{
-		counter:   syncs.NewMap[string, *prometheus.CounterVec](),
-		gauge:     syncs.NewMap[string, *prometheus.GaugeVec](),
-		histogram: syncs.NewMap[string, values.Tuple2[*prometheus.HistogramVec, prometheus.HistogramOpts]](),
-		summary:   syncs.NewMap[string, values.Tuple2[*prometheus.SummaryVec, prometheus.SummaryOpts]](),
-	}
+	c := &client{life: newLifecycle()}
+	c.resetCaches()
 	for _, opt := range opts {
 		opt(c)
 	}
+	c.setup()
+	return c
+}
+
+// With 基于当前 client 派生一个子 client, 可以覆盖除 registry 外的配置
+//
+// 子 client 与当前 client 共享 registry, 内部异常指标及生命周期(任意一个 Close 都会停止所有后台任务).
+// 常量标签不同时, 子 client 使用独立的指标缓存.
+// 默认标签的标签名与当前 client 不同时, 需要同时修改名称空间或子系统(如使用 Sub), 否则同名指标的标签名不一致,
+// 此时通过 logger 输出错误并忽略修改的默认标签.
+//
+//	child := c.Sub("cache", monitor.WithLabels("component", "cache"))
+func (c *client) With(opts ...Opt) *client {
+	child := *c
+	// 后台任务及服务由父 client 启动
+	child.starters, child.server = nil, nil
+	t := c.tuning.Load() // 使用 Reconfigure 后的配置
+	child.buckets, child.objectives = t.buckets, t.objectives
+	child.overrides, child.enabled, child.disabled = t.overrides, t.enabled, t.disabled
+	for _, opt := range opts {
+		opt(&child)
+	}
+	child.registry = c.registry
+	if child.namespace == c.namespace && child.subsystem == c.subsystem &&
+		!slices.Equal(slices.Sorted(maps.Keys(child.labels)), slices.Sorted(maps.Keys(c.labels))) {
+		c.logger(context.Background(), "with|LabelsNeedSubsystem", "labels", child.labels, "parentLabels", c.labels)
+		child.labels = c.labels
+	}
+	if !maps.Equal(child.constLabels, c.constLabels) {
+		child.resetCaches()
+	}
+	child.setup()
+	return &child
+}
+
+// Sub 派生一个子 client, 子系统追加 name, 供各组件使用, 避免指标名冲突
+//
+//	db := c.Sub("db")
+//	// namespace:subsystem:db:counter:query_total
+//	// NamingUnderscore 时为 namespace_subsystem_db_query_total
+//	db.Record(ctx, "query_total", "查询次数")
+func (c *client) Sub(name string, opts ...Opt) *client {
+	subsystem := c.escapeName(name)
+	if c.subsystem != "" {
+		subsystem = c.subsystem + ":" + subsystem
+	}
+	return c.With(append([]Opt{func(c *client) { c.subsystem = subsystem }}, opts...)...)
+}
+
+// resetCaches 使用新的指标缓存
+func (c *client) resetCaches() {
+	c.counter = syncs.NewMap[string, *prometheus.CounterVec]()
+	c.gauge = syncs.NewMap[string, *prometheus.GaugeVec]()
+	c.histogram = syncs.NewMap[string, values.Tuple2[*prometheus.HistogramVec, prometheus.HistogramOpts]]()
+	c.summary = syncs.NewMap[string, values.Tuple2[*prometheus.SummaryVec, prometheus.SummaryOpts]]()
+	c.trackers = syncs.NewMap[string, *tracker]()
+	c.cacheMu = &sync.RWMutex{}
+	c.replaceMu = &sync.RWMutex{}
+	c.replaced = map[string]time.Time{}
+	c.seen = syncs.NewMap[string, *metricInfo]()
+	c.escaped = syncs.NewMap[string, string]()
+	c.escapes = syncs.NewMap[string, string]()
+}
+
+// setup 填充默认值, 并执行 starters
+func (c *client) setup() {
+	if c.utf8Names {
+		c.setupUTF8Names()
+	}
+	c.namespace, c.subsystem = c.escapeName(c.namespace), c.escapeName(c.subsystem)
+	if c.naming == "" {
+		c.naming = NamingColon
+	}
+	c.appends, c.legacy = c.names, c.names
 	if c.names == (NameAppends{}) {
-		c.names = NameAppends{
-			Counter:   NameAppend{Prefix: "counter:"},
-			Gauge:     NameAppend{Prefix: "gauge:"},
-			Timer:     NameAppend{Prefix: "timer:", Suffix: "_seconds"},
-			Histogram: NameAppend{Prefix: "histogram:"},
-			Summary:   NameAppend{Prefix: "summary:"},
-		}
+		c.appends, c.legacy = defaultNameAppends(c.naming), defaultNameAppends(NamingColon)
 	}
 	if c.registry == nil {
 		c.registry = prometheus.NewRegistry()
@@ -83,35 +177,90 @@ func NewClient(opts ...Opt) *client {
 	if c.constLabels == nil {
 		c.constLabels = map[string]string{}
 	}
+	if c.errCounter == nil {
+		c.errCounter = c.newErrCounter()
+	}
 	if c.logger == nil {
 		c.logger = slog.WarnContext
 	}
-	if len(c.buckets) == 0 {
-		_ = prometheus.DefBuckets
-		c.buckets = []float64{ // prometheus.DefBuckets
-			.005, // 5ms
-			.01,  // 10ms
-			.025, // 25ms
-			.05,  // 50ms
-			.1,   // 100ms
-			.25,  // 250ms
-			.5,   // 500ms
-			1,    // 1s
-			2.5,  // 2.5s
-			5,    // 5s
-			10,   // 10s
+	if _, ok := timeUnitSuffixes[c.timeUnit]; !ok {
+		if c.timeUnit != 0 {
+			c.logger(context.Background(), "invalid_time_unit", "unit", c.timeUnit)
+		}
+		c.timeUnit = time.Second
+	}
+	for _, na := range []*NameAppend{&c.appends.Timer, &c.legacy.Timer} {
+		if strings.HasSuffix(na.Suffix, "_seconds") { // 包括 WithNameAppend 设置的后缀, 避免以其他单位记录到 _seconds 指标中
+			na.Suffix = strings.TrimSuffix(na.Suffix, "_seconds") + timeUnitSuffixes[c.timeUnit]
 		}
 	}
+	if len(c.buckets) == 0 {
+		c.buckets = defaultBuckets(c.timeUnit)
+	}
 	if len(c.objectives) == 0 {
 		c.objectives = map[float64]float64{}
 	}
-	return c
+	c.tuning = &atomic.Pointer[tuning]{}
+	t, err := c.newTuning()
+	if err != nil {
+		c.logger(context.Background(), "invalid_metric_pattern", "err", err)
+	}
+	c.tuning.Store(t)
+	if c.classify == nil {
+		c.classify = ClassifyError
+	}
+	if c.mismatch == "" {
+		c.mismatch = MismatchKeepFirst
+	}
+	if len(c.sinks) == 0 {
+		c.sinks = []Sink{PrometheusSink}
+	}
+	c.sinks = slices.Clone(c.sinks)
+	for i, sink := range c.sinks {
+		if _, ok := sink.(*prometheusSink); ok || sink == PrometheusSink {
+			c.sinks[i] = &prometheusSink{c: c} // 派生的子 client 需要重新绑定
+		}
+	}
+	for _, start := range c.starters {
+		start(c)
+	}
+}
+
+// defaultBuckets 默认分布, 按耗时单位换算
+func defaultBuckets(timeUnit time.Duration) []float64 {
+	_ = prometheus.DefBuckets
+	buckets := []float64{ // prometheus.DefBuckets
+		.005, // 5ms
+		.01,  // 10ms
+		.025, // 25ms
+		.05,  // 50ms
+		.1,   // 100ms
+		.25,  // 250ms
+		.5,   // 500ms
+		1,    // 1s
+		2.5,  // 2.5s
+		5,    // 5s
+		10,   // 10s
+	}
+	if timeUnit != time.Second {
+		for i := range buckets {
+			buckets[i] *= float64(time.Second / timeUnit)
+		}
+	}
+	return buckets
+}
+
+// Close 停止 client 的后台任务(如 WithLogDump 定时输出日志), 并等待其退出
+// 可以重复调用. 关闭后仍然可以打点.
+func (c *client) Close() error {
+	return c.life.close()
 }
 
 // EscapeName 对指标名转义
 //
 // 满足 `^[a-zA-Z_:][a-zA-Z0-9_:]*$` 的直接返回,
 // 否则会进行转义: `U__` 开头, 不在上述范围的字符转义为 `_unicode_` 编码.
+// 使用 WithUTF8Names 时 client 不再转义合法的 UTF-8 指标名.
 func EscapeName(s string) string {
 	return model.EscapeName(s, model.ValueEncodingEscaping)
 }
@@ -122,7 +271,7 @@ type Opt func(*client)
 // 默认值为空字符串
 func WithNamespace(name string) Opt {
 	return func(c *client) {
-		c.namespace = EscapeName(name)
+		c.namespace = name // 在 setup 中转义
 	}
 }
 
@@ -130,13 +279,13 @@ func WithNamespace(name string) Opt {
 // 默认值为空字符串
 func WithSubsystem(name string) Opt {
 	return func(c *client) {
-		c.subsystem = EscapeName(name)
+		c.subsystem = name // 在 setup 中转义
 	}
 }
 
 // WithNameAppend 统一设置指标名前缀/后缀
-// 前缀默认值分别是 "counter:" "gauge:" "histogram:" "summary:"
-// 后缀默认值是空字符串
+// 前缀默认值分别是 "counter:" "gauge:" "timer:" "histogram:" "summary:"
+// 后缀默认值除 Timer 为 "_seconds" 外是空字符串; NamingUnderscore 时参见 WithNamingScheme
 func WithNameAppend(nameAppend NameAppends) Opt {
 	return func(c *client) {
 		c.names = nameAppend
@@ -159,6 +308,22 @@ func WithConstLabels(labels map[string]string) Opt {
 	}
 }
 
+// WithLabels 设置默认标签, 每次打点都会附加
+// 与常量标签不同, 默认标签可以被 ctx 上的标签及调用时传入的标签覆盖.
+// 默认值是空的 map
+func WithLabels(kvs ...string) Opt {
+	return func(c *client) {
+		labels := maps.Clone(c.labels)
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		rangeKV(kvs, func(k, v string) {
+			labels[k] = v
+		})
+		c.labels = labels
+	}
+}
+
 // WithLogger 设置日志输出
 // 默认值是 [slog.WarnContext]
 func WithLogger(logger func(context.Context, string, ...any)) Opt {
@@ -184,12 +349,36 @@ func WithObjectives(objectives map[float64]float64) Opt {
 	}
 }
 
+// WithSinks 设置打点事件的接收者
+// 默认值是 [PrometheusSink], 即写入 client 的 registry.
+// 如需同时写入 registry 和其他后端, 需要显式传入 PrometheusSink:
+//
+//	monitor.WithSinks(monitor.PrometheusSink, mySink)
+func WithSinks(sinks ...Sink) Opt {
+	return func(c *client) {
+		c.sinks = sinks
+	}
+}
+
+// WithErrorClassifier 设置错误分类函数, 返回值用作 status 等标签的值
+// 用于 Do, Wrap, RoundTripper, WrapDriver 等记录错误的场景, 参数 err 不会为 nil.
+// 默认值是 [ClassifyError]
+func WithErrorClassifier(classify func(error) string) Opt {
+	return func(c *client) {
+		c.classify = classify
+	}
+}
+
 // Handler 返回一个 http.Handler 用于提供 prometheus 指标数据
 func (c *client) Handler() http.Handler {
-	return promhttp.InstrumentMetricHandler(c.registry,
+	h := promhttp.InstrumentMetricHandler(c.registry,
 		promhttp.HandlerFor(c.registry, promhttp.HandlerOpts{
 			Registry: c.registry,
 		}))
+	if c.utf8Names {
+		return negotiateEscaping(h, c.escaping)
+	}
+	return h
 }
 
 // Registry 返回客户端使用的 registry
@@ -210,40 +399,62 @@ func (c *client) Record(ctx context.Context, name, desc string, kvs ...string) {
 //	// namespace:subsystem:counter:xxx_throughput
 //	c.RecordN(ctx, "xxx_throughput", "打点计数说明", 10)
 func (c *client) RecordN(ctx context.Context, name, desc string, value nums.AnyNumber, kvs ...string) {
-	opt := c.prometheusOpt(name, desc, c.names.Counter)
-	keys, tags := tags(ctx, kvs...)
-	v := c.getCounter(ctx, opt, keys)
-	m, err := v.GetMetricWith(tags)
+	c.emit(ctx, call{kind: KindCounter, names: counterNames, name: name, desc: desc, value: nums.To[float64](value), kvs: kvs})
+}
+
+// writeCounter 将 counter 事件写入 registry
+func (c *client) writeCounter(ctx context.Context, e Event) {
+	v := c.getCounter(ctx, e.prometheusOpt(), e.labelNames())
+	c.noteWrite(e, e.Name)
+	m, err := v.GetMetricWith(e.Labels)
 	if err != nil {
-		c.recordErr(opt.Name, "get_counter")
-		c.logger(ctx, "get_counter|GetMetricWithLabelFailed", "name", opt.Name, "help", desc, "err", err)
+		c.recordErr(e.Name, "get_counter")
+		c.logger(ctx, "get_counter|GetMetricWithLabelFailed", "name", e.Name, "help", e.Help, "err", err)
 		return
 	}
-	m.Add(nums.To[float64](value))
+	m.Add(e.Value)
+}
+
+// metricOpts 指标选项, 额外记录调用时传入的指标名
+type metricOpts struct {
+	prometheus.Opts
+	metric string
+	alias  string // NamingDual 时的旧指标名
 }
 
-func (c *client) prometheusOpt(name, desc string, na NameAppend) prometheus.Opts {
-	return prometheus.Opts{
-		Name:        c.buildFQName(name, na),
-		Help:        desc,
-		ConstLabels: c.constLabels,
+func (c *client) prometheusOpt(name, desc string, kind nameKind) metricOpts {
+	opt := metricOpts{
+		Opts: prometheus.Opts{
+			Name:        c.buildFQName(name, kind(c.appends)),
+			Help:        desc,
+			ConstLabels: c.constLabels,
+		},
+		metric: name,
 	}
+	if c.naming == NamingDual {
+		opt.alias = NamingColon.join(c.namespace, c.subsystem, kind(c.legacy), c.escapeName(name))
+	}
+	return opt
 }
 
 func (c *client) buildFQName(name string, na NameAppend) string {
-	name = na.Prefix + EscapeName(name) + na.Suffix
-	names := iters.Of(c.namespace, c.subsystem, name).
-		Filter(values.IsNotZero).
-		ToSlice()
-	return strings.Join(names, ":")
+	return c.naming.join(c.namespace, c.subsystem, na, c.escapeName(name))
+}
+
+func (c *client) tags(ctx context.Context, kvs ...string) map[string]string {
+	return c.mergeLabels(ctxGetLabels(ctx), kvs...)
 }
 
-func tags(ctx context.Context, kvs ...string) (keys []string, tags map[string]string) {
-	tags = CtxGetLabels(ctx) // 获取 ctx 中的 label
+// mergeLabels 合并默认标签, ctx 上的标签及传入的 kv
+func (c *client) mergeLabels(ctxLabels map[string]string, kvs ...string) (tags map[string]string) {
+	tags = maps.Clone(c.labels) // 默认标签
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	maps.Copy(tags, ctxLabels) // 获取 ctx 中的 label
 	rangeKV(kvs, func(k, v string) {
 		tags[k] = v // 添加传入的 kv, 可能覆盖 ctx 中的
 	})
-	keys = iters.ToSlice(maps.Keys(tags))
 	return
 }
 
@@ -270,18 +481,29 @@ func isAlreadyRegisteredError(err error) bool {
 	return errors.As(err, are)
 }
 
-func (c *client) recordErr(name, kind string) {
-	opt := c.prometheusOpt("internal_monitor_error", "打点异常", c.names.Counter)
-	v := prometheus.NewCounterVec(prometheus.CounterOpts(opt), []string{
+// newErrCounter 创建内部异常指标, 派生的子 client 共享
+func (c *client) newErrCounter() *prometheus.CounterVec {
+	opt := c.prometheusOpt("internal_monitor_error", "打点异常", counterNames)
+	v := prometheus.NewCounterVec(prometheus.CounterOpts(opt.Opts), []string{
 		"name",
 		"kind",
 	})
-	m, _ := v.GetMetricWith(prometheus.Labels{
+	if err := c.registry.Register(v); err != nil {
+		are := &prometheus.AlreadyRegisteredError{}
+		if errors.As(err, are) {
+			if existing, ok := are.ExistingCollector.(*prometheus.CounterVec); ok {
+				return existing // 多个 client 使用同一个 registry
+			}
+		}
+	}
+	return v
+}
+
+func (c *client) recordErr(name, kind string) {
+	c.errCounter.With(prometheus.Labels{
 		"kind": kind,
 		"name": name,
-	})
-	c.registry.Register(m)
-	m.Inc()
+	}).Inc()
 }
 
 // Store 存储当前瞬时值
@@ -289,16 +511,33 @@ func (c *client) recordErr(name, kind string) {
 //	// namespace:subsystem:gauge:current_goroutinue_num
 //	c.Store(ctx, "current_goroutinue_num", "指标含义", 10)
 func (c *client) Store(ctx context.Context, name, desc string, value nums.AnyNumber, kvs ...string) {
-	opt := c.prometheusOpt(name, desc, c.names.Gauge)
-	keys, tags := tags(ctx, kvs...)
-	v := c.getGauge(ctx, opt, keys)
-	m, err := v.GetMetricWith(tags)
+	c.emit(ctx, call{kind: KindGauge, names: gaugeNames, name: name, desc: desc, value: nums.To[float64](value), kvs: kvs})
+}
+
+// Add 在瞬时值上累加 delta, delta 可以为负数
+//
+//	// namespace:subsystem:gauge:in_flight_requests
+//	c.Add(ctx, "in_flight_requests", "处理中的请求数", 1)
+//	defer c.Add(ctx, "in_flight_requests", "处理中的请求数", -1)
+func (c *client) Add(ctx context.Context, name, desc string, delta nums.AnyNumber, kvs ...string) {
+	c.emit(ctx, call{kind: KindGauge, names: gaugeNames, name: name, desc: desc, value: nums.To[float64](delta), add: true, kvs: kvs})
+}
+
+// writeGauge 将 gauge 事件写入 registry
+func (c *client) writeGauge(ctx context.Context, e Event) {
+	v := c.getGauge(ctx, e.prometheusOpt(), e.labelNames())
+	c.noteWrite(e, e.Name)
+	m, err := v.GetMetricWith(e.Labels)
 	if err != nil {
-		c.recordErr(opt.Name, "get_gauge")
-		c.logger(ctx, "get_gauge|GetMetricWithLabelFailed", "name", opt.Name, "help", desc, "err", err)
+		c.recordErr(e.Name, "get_gauge")
+		c.logger(ctx, "get_gauge|GetMetricWithLabelFailed", "name", e.Name, "help", e.Help, "err", err)
 		return
 	}
-	m.Set(nums.To[float64](value))
+	if e.Add {
+		m.Add(e.Value)
+	} else {
+		m.Set(e.Value)
+	}
 }
 
 func (c *client) getGauge(ctx context.Context, o prometheus.Opts, labels []string) *prometheus.GaugeVec {
@@ -319,11 +558,11 @@ func (c *client) getGauge(ctx context.Context, o prometheus.Opts, labels []strin
 //	// namespace:subsystem:timer:some_thing_cost_seconds_count
 //	c.Cost(ctx, "some_thing_cost", "打点说明", time.Since(start))
 func (c *client) Cost(ctx context.Context, name, desc string, cost time.Duration, kvs ...string) {
-	opt := c.prometheusOpt(name, desc, c.names.Timer)
-	c.recordHistogram(ctx, opt, cost.Seconds(), c.buckets, kvs...)
+	c.recordHistogram(ctx, name, desc, timerNames, c.timeValue(cost), nil, kvs...)
 }
 
 // CostBuckets 记录耗时(自定义耗时分布)(使用 Timer 指标前缀/后缀)
+// buckets 为空时同 Cost
 //
 //	start := time.Now()
 //	// do something
@@ -332,33 +571,36 @@ func (c *client) Cost(ctx context.Context, name, desc string, cost time.Duration
 //	// namespace:subsystem:timer:some_thing_cost_seconds_count
 //	c.CostBuckets(ctx, "some_thing_cost", "打点说明", time.Since(start), []float64{1, 2, 3})
 func (c *client) CostBuckets(ctx context.Context, name, desc string, cost time.Duration, buckets []time.Duration, kvs ...string) {
-	secondsBucket := iters.Maps(iters.Of(buckets...), func(d time.Duration) float64 { return d.Seconds() })
-	opt := c.prometheusOpt(name, desc, c.names.Timer)
-	c.recordHistogram(ctx, opt, cost.Seconds(), secondsBucket.ToSlice(), kvs...)
-}
-
-func (c *client) recordHistogram(ctx context.Context, opt prometheus.Opts, value nums.AnyNumber, buckets []float64, kvs ...string) {
-	keys, tags := tags(ctx, kvs...)
-	v := c.getHistogram(ctx, prometheus.HistogramOpts{
-		Name:        opt.Name,
-		Help:        opt.Help,
-		ConstLabels: opt.ConstLabels,
-		Buckets:     buckets,
-	}, keys)
-	if !slices.Equal(v.Val2.Buckets, buckets) {
-		c.recordErr(opt.Name, "histogram_buckets_mismatch")
-		c.logger(ctx, "histogram_buckets_mismatch",
-			"name", opt.Name, "help", opt.Help,
-			"wantBucket", buckets, "actual", v.Val2.Buckets,
-		)
-	}
-	m, err := v.Val1.GetMetricWith(tags)
+	var unitBuckets []float64
+	if len(buckets) > 0 {
+		unitBuckets = iters.Maps(iters.Of(buckets...), c.timeValue).ToSlice()
+	}
+	c.recordHistogram(ctx, name, desc, timerNames, c.timeValue(cost), unitBuckets, kvs...)
+}
+
+// recordHistogram buckets 为空时使用默认分布(可按指标名覆盖, 参见 WithMetricBuckets)
+func (c *client) recordHistogram(ctx context.Context, name, desc string, names nameKind, value nums.AnyNumber, buckets []float64, kvs ...string) {
+	c.emit(ctx, call{kind: KindHistogram, names: names, name: name, desc: desc, value: nums.To[float64](value), buckets: buckets, kvs: kvs})
+}
+
+// writeHistogram 将 histogram 事件写入 registry
+func (c *client) writeHistogram(ctx context.Context, e Event) {
+	if c.mismatch == MismatchReplace {
+		c.replaceMu.RLock()
+		defer c.replaceMu.RUnlock()
+	}
+	v, name, ok := c.histogramFor(ctx, e)
+	if !ok {
+		return
+	}
+	c.noteWrite(e, name)
+	m, err := v.GetMetricWith(e.Labels)
 	if err != nil {
-		c.recordErr(opt.Name, "get_histogram")
-		c.logger(ctx, "get_histogram|GetMetricWithLabelFailed", "name", opt.Name, "help", opt.Help, "err", err)
+		c.recordErr(e.Name, "get_histogram")
+		c.logger(ctx, "get_histogram|GetMetricWithLabelFailed", "name", e.Name, "help", e.Help, "err", err)
 		return
 	}
-	m.Observe(nums.To[float64](value))
+	m.Observe(e.Value)
 }
 
 func (c *client) getHistogram(
@@ -373,7 +615,7 @@ func (c *client) getHistogram(
 
 // Timer 记录耗时(使用 Timer 指标前缀/后缀)
 //
-// @param buckets 如果不传则使用构造 client 时指定的默认值(如果未指定则使用 [prometheus.DefBuckets])
+// @param buckets 如果不传则使用默认值(构造 client 时指定, 如果未指定则使用 [prometheus.DefBuckets]; 可按指标名覆盖)
 // 如果不需要记录耗时分布, 请使用 Observe
 //
 //	timer := c.Timer()
@@ -387,28 +629,68 @@ func (c *client) getHistogram(
 //	// namespace:subsystem:timer:some_thing_cost_seconds_sum
 //	// namespace:subsystem:timer:some_thing_cost_seconds_count
 func (c *client) Timer(buckets ...float64) func(ctx context.Context, name, desc string, kvs ...string) time.Duration {
-	if len(buckets) == 0 {
-		buckets = c.buckets
-	}
 	start := time.Now()
 	return func(ctx context.Context, name, desc string, kvs ...string) time.Duration {
 		cost := time.Since(start)
-		opt := c.prometheusOpt(name, desc, c.names.Timer)
-		c.recordHistogram(ctx, opt, cost.Seconds(), buckets, kvs...)
+		c.recordHistogram(ctx, name, desc, timerNames, c.timeValue(cost), buckets, kvs...)
 		return cost
 	}
 }
 
+// TimerOutcome 记录耗时及结果(使用 Timer 指标前缀/后缀)
+//
+// 同 Timer, 返回的函数额外接收一个 *error (配合 defer 使用时可以取到函数最终返回的错误),
+// 并自动添加 outcome 标签: success, error, canceled, deadline_exceeded (参见 Outcome).
+//
+//	func do(ctx context.Context) (err error) {
+//		defer c.TimerOutcome()(ctx, &err, "some_thing_cost", "打点说明")
+//		// do something
+//	}
+//
+//	// namespace:subsystem:timer:some_thing_cost_seconds_bucket{outcome="success"}
+//	// namespace:subsystem:timer:some_thing_cost_seconds_count{outcome="canceled"}
+func (c *client) TimerOutcome(buckets ...float64) func(ctx context.Context, errp *error, name, desc string, kvs ...string) time.Duration {
+	timer := c.Timer(buckets...)
+	return func(ctx context.Context, errp *error, name, desc string, kvs ...string) time.Duration {
+		return timer(ctx, name, desc, append(kvs[:len(kvs):len(kvs)], "outcome", outcome(ctx, errp))...)
+	}
+}
+
+// Outcome 根据 ctx 及 err 判断操作结果
+//
+// ctx 已取消或 err 为 context.Canceled 时返回 canceled;
+// ctx 已超时或 err 为 context.DeadlineExceeded 时返回 deadline_exceeded;
+// 否则 err 不为 nil 时返回 error, 为 nil 时返回 success.
+func Outcome(ctx context.Context, err error) string {
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled), errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(ctx.Err(), context.DeadlineExceeded), errors.Is(err, context.DeadlineExceeded):
+		return "deadline_exceeded"
+	case err != nil:
+		return "error"
+	default:
+		return "success"
+	}
+}
+
+func outcome(ctx context.Context, errp *error) string {
+	var err error
+	if errp != nil {
+		err = *errp
+	}
+	return Outcome(ctx, err)
+}
+
 // Histogram 记录值的分布. 如果无需记录分布, 请使用 Summary
-// (使用 Histogram 指标前缀/后缀)
+// (使用 Histogram 指标前缀/后缀), buckets 为空时使用默认分布
 //
 //	// namespace:subsystem:histogram:some_thing_cost_bucket
 //	// namespace:subsystem:histogram:some_thing_cost_sum
 //	// namespace:subsystem:histogram:some_thing_cost_count
 //	c.Histogram(ctx, "some_thing_cost", "打点说明", 1.5, []float64{1, 2, 3})
 func (c *client) Histogram(ctx context.Context, name, desc string, value nums.AnyNumber, buckets []float64, kvs ...string) {
-	opt := c.prometheusOpt(name, desc, c.names.Histogram)
-	c.recordHistogram(ctx, opt, value, buckets, kvs...)
+	c.recordHistogram(ctx, name, desc, histogramNames, value, buckets, kvs...)
 }
 
 // Observe 记录耗时摘要(使用 Timer 指标前缀/后缀)
@@ -420,12 +702,23 @@ func (c *client) Observe() func(ctx context.Context, name, desc string, kvs ...s
 	start := time.Now()
 	return func(ctx context.Context, name, desc string, kvs ...string) time.Duration {
 		cost := time.Since(start)
-		opt := c.prometheusOpt(name, desc, c.names.Timer)
-		c.recordSummary(ctx, opt, cost.Seconds(), nil, kvs...)
+		c.recordSummary(ctx, name, desc, timerNames, c.timeValue(cost), map[float64]float64{}, kvs...)
 		return cost
 	}
 }
 
+// ObserveOutcome 记录耗时摘要及结果(使用 Timer 指标前缀/后缀)
+//
+// 同 Observe, 返回的函数额外接收一个 *error, 并自动添加 outcome 标签, 参见 TimerOutcome
+//
+//	defer c.ObserveOutcome()(ctx, &err, "some_thing_cost", "打点说明")
+func (c *client) ObserveOutcome() func(ctx context.Context, errp *error, name, desc string, kvs ...string) time.Duration {
+	observe := c.Observe()
+	return func(ctx context.Context, errp *error, name, desc string, kvs ...string) time.Duration {
+		return observe(ctx, name, desc, append(kvs[:len(kvs):len(kvs)], "outcome", outcome(ctx, errp))...)
+	}
+}
+
 // Summary 记录摘要(使用 Summary 指标前缀/后缀)
 //
 // 使用 client 构造时指定的分位数(默认值是 nil, 表示无需记录分位数, 此时 _sum, _count 指标仍然可用),
@@ -436,7 +729,7 @@ func (c *client) Observe() func(ctx context.Context, name, desc string, kvs ...s
 //	// namespace:subsystem:summary:some_thing_cost_count
 //	c.Summary(ctx, "some_thing_cost", "打点说明", 1.5)
 func (c *client) Summary(ctx context.Context, name, desc string, value nums.AnyNumber, kvs ...string) {
-	c.SummaryObjectives(ctx, name, desc, value, c.objectives, kvs...)
+	c.recordSummary(ctx, name, desc, summaryNames, value, nil, kvs...)
 }
 
 // SummaryObjectives 记录摘要(自定义分位数)(使用 Summary 指标前缀/后缀)
@@ -449,32 +742,35 @@ func (c *client) Summary(ctx context.Context, name, desc string, value nums.AnyN
 //	// namespace:subsystem:summary:some_thing_cost_count
 //	c.SummaryObjectives(ctx, "some_thing_cost", "打点说明", 1.5, map[float64]float64{0.5: 0.05, 0.9: 0.01})
 func (c *client) SummaryObjectives(ctx context.Context, name, desc string, value nums.AnyNumber, objectives map[float64]float64, kvs ...string) {
-	opt := c.prometheusOpt(name, desc, c.names.Summary)
-	c.recordSummary(ctx, opt, value, objectives, kvs...)
-}
-
-func (c *client) recordSummary(ctx context.Context, opt prometheus.Opts, value nums.AnyNumber, objectives map[float64]float64, kvs ...string) {
-	keys, tags := tags(ctx, kvs...)
-	v := c.getSummary(ctx, prometheus.SummaryOpts{
-		Name:        opt.Name,
-		Help:        opt.Help,
-		ConstLabels: opt.ConstLabels,
-		Objectives:  objectives,
-	}, keys)
-	if !maps.Equal(v.Val2.Objectives, objectives) {
-		c.recordErr(opt.Name, "summary_objectives_mismatch")
-		c.logger(ctx, "summary_objectives_mismatch",
-			"name", opt.Name, "help", opt.Help,
-			"wantObjectives", objectives, "actual", v.Val2.Objectives,
-		)
-	}
-	m, err := v.Val1.GetMetricWith(tags)
+	if objectives == nil {
+		objectives = map[float64]float64{}
+	}
+	c.recordSummary(ctx, name, desc, summaryNames, value, objectives, kvs...)
+}
+
+// recordSummary objectives 为 nil 时使用默认分位数(可按指标名覆盖, 参见 WithMetricObjectives)
+func (c *client) recordSummary(ctx context.Context, name, desc string, names nameKind, value nums.AnyNumber, objectives map[float64]float64, kvs ...string) {
+	c.emit(ctx, call{kind: KindSummary, names: names, name: name, desc: desc, value: nums.To[float64](value), objectives: objectives, kvs: kvs})
+}
+
+// writeSummary 将 summary 事件写入 registry
+func (c *client) writeSummary(ctx context.Context, e Event) {
+	if c.mismatch == MismatchReplace {
+		c.replaceMu.RLock()
+		defer c.replaceMu.RUnlock()
+	}
+	v, name, ok := c.summaryFor(ctx, e)
+	if !ok {
+		return
+	}
+	c.noteWrite(e, name)
+	m, err := v.GetMetricWith(e.Labels)
 	if err != nil {
-		c.recordErr(opt.Name, "get_summary")
-		c.logger(ctx, "get_summary|GetMetricWithLabelFailed", "name", opt.Name, "help", opt.Help, "err", err)
+		c.recordErr(e.Name, "get_summary")
+		c.logger(ctx, "get_summary|GetMetricWithLabelFailed", "name", e.Name, "help", e.Help, "err", err)
 		return
 	}
-	m.Observe(nums.To[float64](value))
+	m.Observe(e.Value)
 }
 
 func (c *client) getSummary(ctx context.Context, opt prometheus.SummaryOpts, labels []string) values.Tuple2[*prometheus.SummaryVec, prometheus.SummaryOpts] {
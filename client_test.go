@@ -2,12 +2,15 @@ package monitor_test
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"code.gopub.tech/commons/arg"
+	"code.gopub.tech/commons/assert"
 	"code.gopub.tech/monitor"
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -147,3 +150,68 @@ func TestEscapeName(t *testing.T) {
 		})
 	}
 }
+
+func TestTimerOutcome(t *testing.T) {
+	c := monitor.NewClient()
+	do := func(ctx context.Context, fail bool) (err error) {
+		defer c.TimerOutcome()(ctx, &err, "xxx_cost", "xxx耗时")
+		defer c.ObserveOutcome()(ctx, &err, "xxx_observe", "xxx耗时")
+		if fail {
+			return errors.New("fail")
+		}
+		return nil
+	}
+	do(ctx, false)
+	do(ctx, true)
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	do(canceled, false)
+	expired, cancel := context.WithTimeout(ctx, -1)
+	defer cancel()
+	do(expired, true)
+
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	for _, outcome := range []string{"success", "error", "canceled", "deadline_exceeded"} {
+		assert.True(t, strings.Contains(body, `timer:xxx_cost_seconds_count{outcome="`+outcome+`"} 1`), outcome)
+		assert.True(t, strings.Contains(body, `timer:xxx_observe_seconds_count{outcome="`+outcome+`"} 1`), outcome)
+	}
+	assert.True(t, monitor.Outcome(ctx, context.Canceled) == "canceled")
+}
+
+func TestWith(t *testing.T) {
+	var logs []string
+	c := monitor.NewClient(monitor.WithNamespace("ns"), monitor.WithSubsystem("app"),
+		monitor.WithLogger(func(_ context.Context, msg string, _ ...any) { logs = append(logs, msg) }))
+	db := c.Sub("db", monitor.WithLabels("component", "db"))
+	cache := c.Sub("cache", monitor.WithConstLabels(map[string]string{"tier": "l1"}))
+	sameName := c.With(monitor.WithLabels("k", "v"))
+
+	c.Record(ctx, "xxx_throughput", "xxx总量")
+	db.Record(ctx, "xxx_throughput", "xxx总量")
+	// 调用时传入的标签覆盖默认标签
+	db.Record(ctx, "xxx_throughput", "xxx总量", "component", "db2")
+	cache.Record(ctx, "xxx_throughput", "xxx总量")
+	// 未修改子系统时不能修改默认标签的标签名, 忽略修改的默认标签, 与父 client 写入同一个指标
+	sameName.Record(ctx, "xxx_throughput", "xxx总量")
+	assert.DeepEqual(t, logs, []string{"with|LabelsNeedSubsystem"})
+	// 标签名相同时可以修改默认标签的值
+	db.With(monitor.WithLabels("component", "db3")).Record(ctx, "xxx_throughput", "xxx总量")
+	assert.True(t, c.Registry() == db.Registry())
+
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	t.Log(body)
+	for _, s := range []string{
+		`ns:app:counter:xxx_throughput 2`,
+		`ns:app:db:counter:xxx_throughput{component="db"} 1`,
+		`ns:app:db:counter:xxx_throughput{component="db2"} 1`,
+		`ns:app:db:counter:xxx_throughput{component="db3"} 1`,
+		`ns:app:cache:counter:xxx_throughput{tier="l1"} 1`,
+	} {
+		assert.True(t, strings.Contains(body, s), s)
+	}
+	assert.True(t, !strings.Contains(body, "internal_monitor_error"))
+}
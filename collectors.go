@@ -0,0 +1,88 @@
+package monitor
+
+import (
+	"context"
+	"maps"
+	"runtime/debug"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// NewClient 使用的是空的 registry, 不像全局默认的 prometheus.DefaultRegisterer 那样
+// 自带 go_* 和 process_* 指标, 可以通过以下选项按需注册.
+
+// WithGoCollector 注册 Go 运行时指标 go_*
+//
+// rules 用于选择额外暴露的 runtime/metrics 指标, 不传则只暴露默认的指标.
+//
+//	monitor.WithGoCollector(collectors.MetricsGC, collectors.MetricsScheduler)
+func WithGoCollector(rules ...collectors.GoRuntimeMetricsRule) Opt {
+	return WithCollectors(collectors.NewGoCollector(collectors.WithGoCollectorRuntimeMetrics(rules...)))
+}
+
+// WithProcessCollector 注册进程指标 process_* (CPU, 内存, 文件描述符等)
+func WithProcessCollector() Opt {
+	return WithCollectors(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+}
+
+// WithBuildInfo 注册构建信息指标
+//
+// 包括标准的 go_build_info{path, version, checksum},
+// 以及带有 VCS 信息的 build_info 指标(使用 Gauge 指标前缀/后缀, 值恒为 1):
+//
+//	// namespace:subsystem:gauge:build_info{go_version="go1.23.0",path="...",version="v1.0.0",revision="...",modified="false"} 1
+func WithBuildInfo() Opt {
+	return func(c *client) {
+		c.starters = append(c.starters, func(c *client) {
+			c.register(context.Background(), collectors.NewBuildInfoCollector(), "go_build_info", "", "register_collector")
+			info, ok := debug.ReadBuildInfo()
+			if !ok {
+				return
+			}
+			labels := prometheus.Labels{
+				"go_version": info.GoVersion,
+				"path":       info.Main.Path,
+				"version":    info.Main.Version,
+				"revision":   "",
+				"modified":   "",
+			}
+			for _, s := range info.Settings {
+				switch s.Key {
+				case "vcs.revision":
+					labels["revision"] = s.Value
+				case "vcs.modified":
+					labels["modified"] = s.Value
+				}
+			}
+			opt := c.prometheusOpt("build_info", "构建信息", gaugeNames)
+			g := prometheus.NewGauge(prometheus.GaugeOpts{
+				Name:        opt.Name,
+				Help:        opt.Help,
+				ConstLabels: mergeLabels(opt.ConstLabels, labels),
+			})
+			g.Set(1)
+			c.register(context.Background(), g, opt.Name, opt.Help, "register_collector")
+		})
+	}
+}
+
+// WithCollectors 注册自定义的 prometheus.Collector
+func WithCollectors(cs ...prometheus.Collector) Opt {
+	return func(c *client) {
+		c.starters = append(c.starters, func(c *client) {
+			for _, collector := range cs {
+				c.register(context.Background(), collector, "", "", "register_collector")
+			}
+		})
+	}
+}
+
+// mergeLabels 合并多个 map, 后面的覆盖前面的
+func mergeLabels(ms ...map[string]string) map[string]string {
+	result := map[string]string{}
+	for _, m := range ms {
+		maps.Copy(result, m)
+	}
+	return result
+}
@@ -0,0 +1,31 @@
+package monitor_test
+
+import (
+	"strings"
+	"testing"
+
+	"code.gopub.tech/commons/assert"
+	"code.gopub.tech/monitor"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+func TestCollectors(t *testing.T) {
+	c := monitor.NewClient(
+		monitor.WithNamespace("ns"),
+		monitor.WithGoCollector(collectors.MetricsGC),
+		monitor.WithProcessCollector(),
+		monitor.WithBuildInfo(),
+	)
+	families, err := c.Registry().Gather()
+	assert.True(t, err == nil)
+	names := map[string]bool{}
+	var hasGC bool
+	for _, mf := range families {
+		names[mf.GetName()] = true
+		hasGC = hasGC || strings.HasPrefix(mf.GetName(), "go_gc_")
+	}
+	assert.True(t, names["go_goroutines"])
+	assert.True(t, names["go_build_info"])
+	assert.True(t, names["ns:gauge:build_info"])
+	assert.True(t, hasGC)
+}
@@ -0,0 +1,466 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"code.gopub.tech/commons/choose"
+	"gopkg.in/yaml.v3"
+)
+
+// Config client 配置, 可以从 YAML/JSON 及环境变量加载, 便于按部署环境调整指标命名及分布而无需重新编译
+//
+//	namespace: app
+//	subsystem: api
+//	naming_scheme: colon # 或 underscore, dual, 参见 WithNamingScheme
+//	name_appends:
+//	  counter: {prefix: "counter:"}
+//	const_labels: {idc: bj}
+//	buckets: [0.01, 0.1, 1, 10]
+//	objectives: {"0.5": 0.05, "0.99": 0.001}
+//	server: {addr: ":9100", pprof: true}
+//	metrics:
+//	  - {name: "db_*", buckets: [0.001, 0.01, 0.1]}
+//	  - {name: "re:^batch_", buckets: [60, 300, 1800]}
+//	disabled_metrics: [debug_events]
+type Config struct {
+	Namespace        string             `json:"namespace" yaml:"namespace"`
+	Subsystem        string             `json:"subsystem" yaml:"subsystem"`
+	NamingScheme     NamingScheme       `json:"naming_scheme" yaml:"naming_scheme"`
+	UTF8Names        bool               `json:"utf8_names" yaml:"utf8_names"`       // 参见 WithUTF8Names, 需要先调用 EnableUTF8Names
+	NameEscaping     NameEscaping       `json:"name_escaping" yaml:"name_escaping"` // utf8_names 时不支持 UTF-8 的抓取方使用的转义方式
+	NameAppends      NameAppends        `json:"name_appends" yaml:"name_appends"`
+	ConstLabels      map[string]string  `json:"const_labels" yaml:"const_labels"`
+	Labels           map[string]string  `json:"labels" yaml:"labels"`
+	Buckets          []float64          `json:"buckets" yaml:"buckets"`
+	Objectives       map[string]float64 `json:"objectives" yaml:"objectives"` // 分位数 => 允许误差
+	MismatchStrategy MismatchStrategy   `json:"mismatch_strategy" yaml:"mismatch_strategy"`
+	StrictSchema     bool               `json:"strict_schema" yaml:"strict_schema"`
+	GoCollector      bool               `json:"go_collector" yaml:"go_collector"`
+	ProcessCollector bool               `json:"process_collector" yaml:"process_collector"`
+	BuildInfo        bool               `json:"build_info" yaml:"build_info"`
+	Server           ServerConfig       `json:"server" yaml:"server"`
+	Metrics          []MetricConfig     `json:"metrics" yaml:"metrics"`                   // 参见 WithMetricBuckets, WithMetricObjectives
+	EnabledMetrics   []string           `json:"enabled_metrics" yaml:"enabled_metrics"`   // 参见 WithEnabledMetrics
+	DisabledMetrics  []string           `json:"disabled_metrics" yaml:"disabled_metrics"` // 参见 WithDisabledMetrics
+}
+
+// MetricConfig 匹配的指标的默认分布/分位数
+type MetricConfig struct {
+	Name       string             `json:"name" yaml:"name"` // 指标名模式, 参见 WithMetricBuckets
+	Buckets    []float64          `json:"buckets" yaml:"buckets"`
+	Objectives map[string]float64 `json:"objectives" yaml:"objectives"`
+}
+
+// ServerConfig 独立指标服务的配置, 参见 Serve
+// Addr 不为空时 Opts 包含 WithServer, 构造 client 后在后台启动服务
+type ServerConfig struct {
+	Addr     string `json:"addr" yaml:"addr"`
+	Pattern  string `json:"pattern" yaml:"pattern"`
+	Pprof    bool   `json:"pprof" yaml:"pprof"`
+	CertFile string `json:"cert_file" yaml:"cert_file"`
+	KeyFile  string `json:"key_file" yaml:"key_file"`
+}
+
+var (
+	nameAppendRegexp = regexp.MustCompile(`^[a-zA-Z0-9_:]*$`)
+)
+
+// NewClientFromConfig 从 YAML/JSON 配置新建 client, opts 在配置之后生效
+// 配置了 server.addr 时在后台启动独立指标服务, 需要调用 Close 关闭
+//
+//	f, _ := os.Open("monitor.yaml")
+//	c, err := monitor.NewClientFromConfig(f, monitor.WithEnv("MONITOR"))
+//	defer c.Close()
+func NewClientFromConfig(r io.Reader, opts ...Opt) (*client, error) {
+	cfg, err := LoadConfig(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(append(cfg.Opts(), opts...)...), nil
+}
+
+// LoadConfig 读取并校验 YAML/JSON 配置, 未配置的 name_appends 使用 naming_scheme 对应的默认值
+func LoadConfig(r io.Reader) (*Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("monitor: read config: %w", err)
+	}
+	var scheme struct {
+		NamingScheme NamingScheme `yaml:"naming_scheme"`
+	}
+	yaml.Unmarshal(data, &scheme) // 格式错误在下面统一返回
+	cfg := &Config{NameAppends: defaultNameAppends(scheme.NamingScheme)}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("monitor: parse config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// WithEnv 从环境变量读取配置, 覆盖之前的选项
+// 环境变量格式错误时通过 logger 输出, 并忽略所有环境变量. 支持的环境变量参见 Config.LoadEnv
+func WithEnv(prefix string) Opt {
+	return func(c *client) {
+		cfg := configOf(c)
+		if err := cfg.LoadEnv(prefix); err != nil {
+			logger := choose.If(c.logger != nil, c.logger, slog.WarnContext)
+			logger(context.Background(), "config|InvalidEnv", "prefix", prefix, "err", err)
+			return
+		}
+		for _, opt := range cfg.Opts() {
+			opt(c)
+		}
+	}
+}
+
+// configOf 返回 client 当前的配置
+func configOf(c *client) *Config {
+	cfg := &Config{
+		Namespace:        c.namespace,
+		Subsystem:        c.subsystem,
+		NamingScheme:     c.naming,
+		UTF8Names:        c.utf8Names,
+		NameEscaping:     c.escaping,
+		NameAppends:      c.names,
+		ConstLabels:      maps.Clone(c.constLabels),
+		Labels:           maps.Clone(c.labels),
+		Buckets:          slices.Clone(c.buckets),
+		MismatchStrategy: c.mismatch,
+		StrictSchema:     c.strictSchema,
+		EnabledMetrics:   c.enabled,
+		DisabledMetrics:  c.disabled,
+	}
+	if cfg.NameAppends == (NameAppends{}) {
+		cfg.NameAppends = defaultNameAppends(c.naming)
+	}
+	if c.server != nil {
+		cfg.Server = *c.server
+	}
+	if len(c.objectives) > 0 {
+		cfg.Objectives = map[string]float64{}
+		for q, e := range c.objectives {
+			cfg.Objectives[strconv.FormatFloat(q, 'g', -1, 64)] = e
+		}
+	}
+	return cfg
+}
+
+// LoadEnv 从环境变量读取配置, 覆盖已有的值, 未设置的环境变量不影响已有的值
+//
+// 以 prefix 为 MONITOR 为例, 支持的环境变量有:
+//
+//	MONITOR_NAMESPACE=app
+//	MONITOR_SUBSYSTEM=api
+//	MONITOR_NAMING_SCHEME=underscore      // 未设置前缀/后缀时同时切换为对应的默认前缀/后缀
+//	MONITOR_COUNTER_PREFIX=counter:      // 以及 _SUFFIX, 指标类型还有 GAUGE, TIMER, HISTOGRAM, SUMMARY
+//	MONITOR_UTF8_NAMES=true              // 以及 NAME_ESCAPING
+//	MONITOR_CONST_LABELS=idc=bj,env=prod
+//	MONITOR_LABELS=k=v
+//	MONITOR_BUCKETS=0.01,0.1,1,10
+//	MONITOR_OBJECTIVES=0.5:0.05,0.99:0.001
+//	MONITOR_DISABLED_METRICS=debug_events // 以及 ENABLED_METRICS
+//	MONITOR_GO_COLLECTOR=true            // 以及 PROCESS_COLLECTOR, BUILD_INFO, STRICT_SCHEMA
+//	MONITOR_SERVER_ADDR=:9100            // 以及 SERVER_PATTERN, SERVER_PPROF, SERVER_CERT_FILE, SERVER_KEY_FILE
+func (cfg *Config) LoadEnv(prefix string) error {
+	if prefix != "" && !strings.HasSuffix(prefix, "_") {
+		prefix += "_"
+	}
+	var errs []error
+	env := func(key string, parse func(string) error) {
+		key = prefix + key
+		if v, ok := os.LookupEnv(key); ok {
+			if err := parse(v); err != nil {
+				errs = append(errs, fmt.Errorf("monitor: env %s: %w", key, err))
+			}
+		}
+	}
+	str := func(p *string) func(string) error {
+		return func(v string) error { *p = v; return nil }
+	}
+	boolean := func(p *bool) func(string) error {
+		return func(v string) (err error) { *p, err = strconv.ParseBool(v); return }
+	}
+	labels := func(p *map[string]string) func(string) error {
+		return func(v string) (err error) {
+			*p, err = parsePairs(v, "=", func(s string) (string, error) { return s, nil })
+			return
+		}
+	}
+	env("NAMESPACE", str(&cfg.Namespace))
+	env("SUBSYSTEM", str(&cfg.Subsystem))
+	env("NAMING_SCHEME", func(v string) error {
+		scheme := NamingScheme(v)
+		if cfg.NameAppends == defaultNameAppends(cfg.NamingScheme) {
+			cfg.NameAppends = defaultNameAppends(scheme)
+		}
+		cfg.NamingScheme = scheme
+		return nil
+	})
+	for kind, na := range map[string]*NameAppend{
+		"COUNTER":   &cfg.NameAppends.Counter,
+		"GAUGE":     &cfg.NameAppends.Gauge,
+		"TIMER":     &cfg.NameAppends.Timer,
+		"HISTOGRAM": &cfg.NameAppends.Histogram,
+		"SUMMARY":   &cfg.NameAppends.Summary,
+	} {
+		env(kind+"_PREFIX", str(&na.Prefix))
+		env(kind+"_SUFFIX", str(&na.Suffix))
+	}
+	env("UTF8_NAMES", boolean(&cfg.UTF8Names))
+	env("NAME_ESCAPING", func(v string) error { cfg.NameEscaping = NameEscaping(v); return nil })
+	env("CONST_LABELS", labels(&cfg.ConstLabels))
+	env("LABELS", labels(&cfg.Labels))
+	env("BUCKETS", func(v string) error {
+		cfg.Buckets = nil
+		for _, s := range splitList(v) {
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return fmt.Errorf("invalid bucket %q", s)
+			}
+			cfg.Buckets = append(cfg.Buckets, f)
+		}
+		return nil
+	})
+	env("OBJECTIVES", func(v string) (err error) {
+		cfg.Objectives, err = parsePairs(v, ":", func(s string) (float64, error) { return strconv.ParseFloat(s, 64) })
+		return
+	})
+	env("ENABLED_METRICS", func(v string) error { cfg.EnabledMetrics = splitList(v); return nil })
+	env("DISABLED_METRICS", func(v string) error { cfg.DisabledMetrics = splitList(v); return nil })
+	env("STRICT_SCHEMA", boolean(&cfg.StrictSchema))
+	env("GO_COLLECTOR", boolean(&cfg.GoCollector))
+	env("PROCESS_COLLECTOR", boolean(&cfg.ProcessCollector))
+	env("BUILD_INFO", boolean(&cfg.BuildInfo))
+	env("SERVER_ADDR", str(&cfg.Server.Addr))
+	env("SERVER_PATTERN", str(&cfg.Server.Pattern))
+	env("SERVER_PPROF", boolean(&cfg.Server.Pprof))
+	env("SERVER_CERT_FILE", str(&cfg.Server.CertFile))
+	env("SERVER_KEY_FILE", str(&cfg.Server.KeyFile))
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return cfg.Validate()
+}
+
+// splitList 按逗号分隔, 忽略空白
+func splitList(s string) []string {
+	var result []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// parsePairs 解析 k1=v1,k2=v2 格式
+func parsePairs[V any](s, sep string, parse func(string) (V, error)) (map[string]V, error) {
+	result := map[string]V{}
+	for _, item := range splitList(s) {
+		k, v, ok := strings.Cut(item, sep)
+		if !ok {
+			return nil, fmt.Errorf("invalid pair %q, want key%svalue", item, sep)
+		}
+		value, err := parse(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of %q: %w", item, err)
+		}
+		result[strings.TrimSpace(k)] = value
+	}
+	return result, nil
+}
+
+// Validate 校验配置, 返回所有错误
+func (cfg *Config) Validate() error {
+	var errs []error
+	invalid := func(field, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("monitor: config %s: "+format, append([]any{field}, args...)...))
+	}
+	for kind, na := range map[string]NameAppend{
+		"counter":   cfg.NameAppends.Counter,
+		"gauge":     cfg.NameAppends.Gauge,
+		"timer":     cfg.NameAppends.Timer,
+		"histogram": cfg.NameAppends.Histogram,
+		"summary":   cfg.NameAppends.Summary,
+	} {
+		if !nameAppendRegexp.MatchString(na.Prefix) || !nameAppendRegexp.MatchString(na.Suffix) {
+			invalid("name_appends."+kind, "prefix %q / suffix %q may only contain [a-zA-Z0-9_:]", na.Prefix, na.Suffix)
+		}
+	}
+	for field, labels := range map[string]map[string]string{"const_labels": cfg.ConstLabels, "labels": cfg.Labels} {
+		for name, value := range labels {
+			if err := checkLabel(name, value, cfg.UTF8Names); err != nil {
+				invalid(field, "%v", err)
+			}
+		}
+	}
+	if err := checkBuckets(cfg.Buckets); err != nil {
+		invalid("buckets", "%v", err)
+	}
+	if _, err := parseObjectives(cfg.Objectives); err != nil {
+		invalid("objectives", "%v", err)
+	}
+	switch cfg.NamingScheme {
+	case "", NamingColon, NamingUnderscore, NamingDual:
+	default:
+		invalid("naming_scheme", "unknown scheme %q", cfg.NamingScheme)
+	}
+	switch cfg.NameEscaping {
+	case "", EscapingUnderscores, EscapingDots, EscapingValues:
+	default:
+		invalid("name_escaping", "unknown escaping %q", cfg.NameEscaping)
+	}
+	switch cfg.MismatchStrategy {
+	case "", MismatchKeepFirst, MismatchSibling, MismatchReplace, MismatchReject:
+	default:
+		invalid("mismatch_strategy", "unknown strategy %q", cfg.MismatchStrategy)
+	}
+	for i, m := range cfg.Metrics {
+		field := fmt.Sprintf("metrics[%d]", i)
+		if m.Name == "" {
+			invalid(field, "name is required")
+		} else if _, err := compileMatcher(m.Name); err != nil {
+			invalid(field+".name", "%v", err)
+		}
+		if err := checkBuckets(m.Buckets); err != nil {
+			invalid(field+".buckets", "%v", err)
+		}
+		if _, err := parseObjectives(m.Objectives); err != nil {
+			invalid(field+".objectives", "%v", err)
+		}
+	}
+	for field, patterns := range map[string][]string{"enabled_metrics": cfg.EnabledMetrics, "disabled_metrics": cfg.DisabledMetrics} {
+		if _, err := compileMatchers(patterns); err != nil {
+			invalid(field, "%v", err)
+		}
+	}
+	if (cfg.Server.CertFile == "") != (cfg.Server.KeyFile == "") {
+		invalid("server", "cert_file and key_file must be set together")
+	}
+	return errors.Join(errs...)
+}
+
+func checkBuckets(buckets []float64) error {
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] <= buckets[i-1] {
+			return fmt.Errorf("must be in strictly increasing order, got %v", buckets)
+		}
+	}
+	return nil
+}
+
+func parseObjectives(objectives map[string]float64) (map[float64]float64, error) {
+	if len(objectives) == 0 {
+		return nil, nil
+	}
+	result := map[float64]float64{}
+	for k, e := range objectives {
+		q, err := strconv.ParseFloat(k, 64)
+		if err != nil || q < 0 || q > 1 {
+			return nil, fmt.Errorf("quantile %q must be a number in [0, 1]", k)
+		}
+		if e <= 0 || e >= 1 {
+			return nil, fmt.Errorf("error of quantile %q must be in (0, 1), got %v", k, e)
+		}
+		result[q] = e
+	}
+	return result, nil
+}
+
+// Opts 将配置转换为 client 选项, 配置需要先经过 Validate 校验
+func (cfg *Config) Opts() []Opt {
+	opts := []Opt{
+		WithNamespace(cfg.Namespace),
+		WithSubsystem(cfg.Subsystem),
+		WithNamingScheme(cfg.NamingScheme),
+		WithNameAppend(cfg.NameAppends),
+		WithConstLabels(maps.Clone(cfg.ConstLabels)),
+		func(c *client) { c.labels = maps.Clone(cfg.Labels) },
+	}
+	if cfg.NameAppends == defaultNameAppends(cfg.NamingScheme) {
+		opts[3] = WithNameAppend(NameAppends{}) // 使用命名方式的默认值, 派生的子 client 切换命名方式时随之切换
+	}
+	if cfg.UTF8Names {
+		opts = append(opts, WithUTF8Names(cfg.NameEscaping))
+	}
+	if cfg.MismatchStrategy != "" {
+		opts = append(opts, WithMismatchStrategy(cfg.MismatchStrategy))
+	}
+	opts = append(opts, WithStrictSchema(cfg.StrictSchema))
+	opts = append(opts, cfg.TuningOpts()...)
+	if cfg.GoCollector {
+		opts = append(opts, WithGoCollector())
+	}
+	if cfg.ProcessCollector {
+		opts = append(opts, WithProcessCollector())
+	}
+	if cfg.BuildInfo {
+		opts = append(opts, WithBuildInfo())
+	}
+	return opts
+}
+
+// TuningOpts 将运行时可以调整的配置转换为 client 选项, 可用于 Reconfigure, 未配置的项不会生成选项
+func (cfg *Config) TuningOpts() []Opt {
+	var opts []Opt
+	if len(cfg.Buckets) > 0 {
+		opts = append(opts, WithBuckets(slices.Clone(cfg.Buckets)))
+	}
+	if objectives, _ := parseObjectives(cfg.Objectives); objectives != nil {
+		opts = append(opts, WithObjectives(objectives))
+	}
+	for _, m := range cfg.Metrics {
+		if len(m.Buckets) > 0 {
+			opts = append(opts, WithMetricBuckets(m.Name, slices.Clone(m.Buckets)...))
+		}
+		if objectives, _ := parseObjectives(m.Objectives); objectives != nil {
+			opts = append(opts, WithMetricObjectives(m.Name, objectives))
+		}
+	}
+	if cfg.EnabledMetrics != nil {
+		opts = append(opts, WithEnabledMetrics(slices.Clone(cfg.EnabledMetrics)...))
+	}
+	if cfg.DisabledMetrics != nil {
+		opts = append(opts, WithDisabledMetrics(slices.Clone(cfg.DisabledMetrics)...))
+	}
+	if cfg.Server.Addr != "" {
+		opts = append(opts, WithServer(cfg.Server))
+	}
+	return opts
+}
+
+// ServeOpts 将 server 配置转换为 Serve 的选项
+//
+//	go c.Serve(ctx, cfg.Server.Addr, cfg.ServeOpts()...)
+func (cfg *Config) ServeOpts() []ServeOpt {
+	return cfg.Server.opts()
+}
+
+func (sc ServerConfig) opts() []ServeOpt {
+	var opts []ServeOpt
+	if sc.Pattern != "" {
+		opts = append(opts, ServePattern(sc.Pattern))
+	}
+	if sc.Pprof {
+		opts = append(opts, ServePprof())
+	}
+	if sc.CertFile != "" {
+		opts = append(opts, ServeTLS(sc.CertFile, sc.KeyFile))
+	}
+	return opts
+}
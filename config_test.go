@@ -0,0 +1,105 @@
+package monitor_test
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"code.gopub.tech/commons/assert"
+	"code.gopub.tech/monitor"
+)
+
+func TestNewClientFromConfig(t *testing.T) {
+	yamlConfig := `
+namespace: app
+subsystem: api
+name_appends:
+  counter: {prefix: "c:"}
+const_labels: {idc: bj}
+buckets: [0.1, 1]
+server: {pattern: /m, pprof: true}
+`
+	c, err := monitor.NewClientFromConfig(strings.NewReader(yamlConfig))
+	assert.True(t, err == nil, err)
+	defer c.Close()
+	c.Record(ctx, "req", "请求数")
+	c.Cost(ctx, "latency", "耗时", time.Millisecond*500)
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	t.Log(body)
+	assert.True(t, strings.Contains(body, `app:api:c:req{idc="bj"} 1`))
+	// 未配置的前缀使用默认值
+	assert.True(t, strings.Contains(body, `app:api:timer:latency_seconds_bucket{idc="bj",le="1"} 1`))
+
+	// JSON 也可以
+	cfg, err := monitor.LoadConfig(strings.NewReader(`{"namespace": "app", "objectives": {"0.5": 0.05}}`))
+	assert.True(t, err == nil, err)
+	assert.True(t, cfg.Namespace == "app")
+	assert.True(t, cfg.NameAppends.Counter.Prefix == "counter:")
+
+	cfg, err = monitor.LoadConfig(strings.NewReader(yamlConfig))
+	assert.True(t, err == nil, err)
+	assert.True(t, len(cfg.ServeOpts()) == 2)
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		config string
+		want   string
+	}{
+		{`namespace: [1]`, "parse config"},
+		{`unknown: 1`, "field unknown not found"},
+		{`const_labels: {1a: v}`, `const_labels: invalid label name "1a"`},
+		{`labels: {__x: v}`, `labels: invalid label name "__x"`},
+		{`buckets: [1, 0.5]`, "buckets: must be in strictly increasing order"},
+		{`objectives: {"1.5": 0.01}`, `quantile "1.5" must be a number in [0, 1]`},
+		{`objectives: {"0.5": 0}`, `error of quantile "0.5"`},
+		{`name_appends: {gauge: {prefix: "g-"}}`, "name_appends.gauge"},
+		{`server: {cert_file: a.pem}`, "cert_file and key_file must be set together"},
+	} {
+		_, err := monitor.LoadConfig(strings.NewReader(tc.config))
+		assert.True(t, err != nil && strings.Contains(err.Error(), tc.want), tc.config, err)
+	}
+}
+
+func TestWithEnv(t *testing.T) {
+	t.Setenv("TEST_MONITOR_NAMESPACE", "env")
+	t.Setenv("TEST_MONITOR_COUNTER_PREFIX", "")
+	t.Setenv("TEST_MONITOR_CONST_LABELS", "idc=sh, env=prod")
+	t.Setenv("TEST_MONITOR_BUCKETS", "0.5, 2")
+	t.Setenv("TEST_MONITOR_OBJECTIVES", "0.9:0.01")
+	c := monitor.NewClient(
+		monitor.WithSubsystem("sub"),
+		monitor.WithEnv("TEST_MONITOR"),
+	)
+	c.Record(ctx, "req", "请求数")
+	c.Cost(ctx, "latency", "耗时", time.Second)
+	c.Summary(ctx, "size", "大小", 1)
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	t.Log(body)
+	// 环境变量覆盖之前的选项, 未设置的保持不变
+	assert.True(t, strings.Contains(body, `env:sub:req{env="prod",idc="sh"} 1`))
+	assert.True(t, strings.Contains(body, `env:sub:timer:latency_seconds_bucket{env="prod",idc="sh",le="2"} 1`))
+	assert.True(t, strings.Contains(body, `env:sub:summary:size{env="prod",idc="sh",quantile="0.9"} 1`))
+
+	// 格式错误时忽略所有环境变量
+	t.Setenv("TEST_MONITOR_BUCKETS", "x")
+	var logs []string
+	c = monitor.NewClient(
+		monitor.WithLogger(func(_ context.Context, msg string, args ...any) {
+			logs = append(logs, msg+fmt.Sprint(args...))
+		}),
+		monitor.WithEnv("TEST_MONITOR"),
+	)
+	c.Record(ctx, "req", "请求数")
+	w = httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.True(t, strings.Contains(w.Body.String(), `counter:req 1`))
+	assert.True(t, len(logs) == 1 && strings.Contains(logs[0], "TEST_MONITOR_BUCKETS"), logs)
+}
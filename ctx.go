@@ -3,20 +3,60 @@ package monitor
 import (
 	"context"
 	"maps"
+	"sync"
 )
 
 type ctxKey struct{}
 
+type scopeKey struct{}
+
+// labelScope 可变的标签容器
+// 由 HTTPMiddleware 等放到 ctx 上, 使下游通过 CtxAddLabels 添加的标签能够被上游看到
+type labelScope struct {
+	mu     sync.Mutex
+	labels map[string]string
+}
+
 // CtxAddLabels 往 ctx 中添加 labels, 返回新的 ctx
+//
+// 如果 ctx 来自 HTTPMiddleware 等, 添加的 labels 中由 HTTPScopeLabels 指定的标签也会同步到中间件记录的指标上.
 func CtxAddLabels(ctx context.Context, kvs ...string) context.Context {
 	var m = CtxGetLabels(ctx)
 	rangeKV(kvs, func(k, v string) {
 		m[k] = v
 	})
+	if scope, ok := ctx.Value(scopeKey{}).(*labelScope); ok {
+		scope.add(kvs)
+	}
 	ctx = context.WithValue(ctx, ctxKey{}, m)
 	return ctx
 }
 
+// ctxWithLabelScope 在 ctx 上放置一个新的标签容器
+func ctxWithLabelScope(ctx context.Context) (context.Context, *labelScope) {
+	scope := &labelScope{labels: map[string]string{}}
+	return context.WithValue(ctx, scopeKey{}, scope), scope
+}
+
+func (s *labelScope) add(kvs []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rangeKV(kvs, func(k, v string) {
+		s.labels[k] = v
+	})
+}
+
+// kvs 返回 names 对应的标签对, 未添加的标签值为空字符串, 使标签名保持一致
+func (s *labelScope) kvs(names []string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kvs := make([]string, 0, len(names)*2)
+	for _, name := range names {
+		kvs = append(kvs, name, s.labels[name])
+	}
+	return kvs
+}
+
 // CtxGetLabels 从 ctx 中获取 labels
 func CtxGetLabels(ctx context.Context) map[string]string {
 	m := ctxGetLabels(ctx)
@@ -0,0 +1,317 @@
+package monitor
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// RegisterDBStats 注册 sql.DB 连接池指标(db.Stats())
+// 包括打开/使用中/空闲连接数, 等待次数, 等待耗时等, 指标名为 go_sql_*, 带有 db_name 常量标签.
+func (c *client) RegisterDBStats(db *sql.DB, dbName string) {
+	c.register(context.Background(), collectors.NewDBStatsCollector(db, dbName), dbName, "", "register_db_stats")
+}
+
+// WrapDriver 包装 driver.Driver, 记录 SQL 操作耗时(使用 Timer 指标前缀/后缀)
+//
+// 标签为 db, operation, status 以及 ctx 上的标签.
+// operation 取值为 query, exec, prepare, begin, commit, rollback;
+// status 成功时为 ok, 失败时为错误分类.
+//
+//	// namespace:subsystem:timer:sql_seconds{db="main",operation="query",status="ok"}
+//	sql.Register("mysql-monitored", c.WrapDriver(&mysql.MySQLDriver{}, "main"))
+//	db, err := sql.Open("mysql-monitored", dsn)
+func (c *client) WrapDriver(d driver.Driver, dbName string) driver.Driver {
+	return &sqlDriver{base: d, rec: &sqlRecorder{c: c, db: dbName}}
+}
+
+// WrapConnector 包装 driver.Connector, 记录 SQL 操作耗时, 参见 WrapDriver
+//
+//	db := sql.OpenDB(c.WrapConnector(connector, "main"))
+func (c *client) WrapConnector(cn driver.Connector, dbName string) driver.Connector {
+	return &sqlConnector{base: cn, rec: &sqlRecorder{c: c, db: dbName}}
+}
+
+type sqlRecorder struct {
+	c  *client
+	db string
+}
+
+// record 记录一次操作的耗时, driver.ErrSkip 表示驱动不支持该操作, 不记录
+func (r *sqlRecorder) record(ctx context.Context, operation string, start time.Time, err error) {
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+	status := "ok"
+	if err != nil {
+		status = r.c.classify(err)
+	}
+	r.c.Cost(ctx, "sql", "SQL 操作耗时", time.Since(start), "db", r.db, "operation", operation, "status", status)
+}
+
+type sqlDriver struct {
+	base driver.Driver
+	rec  *sqlRecorder
+}
+
+func (d *sqlDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.base.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn{base: conn, rec: d.rec}, nil
+}
+
+// OpenConnector 实现 driver.DriverContext
+func (d *sqlDriver) OpenConnector(name string) (driver.Connector, error) {
+	if dc, ok := d.base.(driver.DriverContext); ok {
+		cn, err := dc.OpenConnector(name)
+		if err != nil {
+			return nil, err
+		}
+		return &sqlConnector{base: cn, rec: d.rec, driver: d}, nil
+	}
+	return &sqlConnector{base: dsnConnector{name: name, driver: d.base}, rec: d.rec, driver: d}, nil
+}
+
+// dsnConnector 用于没有实现 driver.DriverContext 的驱动
+type dsnConnector struct {
+	name   string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open(c.name) }
+func (c dsnConnector) Driver() driver.Driver                        { return c.driver }
+
+type sqlConnector struct {
+	base   driver.Connector
+	rec    *sqlRecorder
+	driver driver.Driver // 通过 WrapDriver 创建时为包装后的 driver
+}
+
+func (cn *sqlConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := cn.base.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn{base: conn, rec: cn.rec}, nil
+}
+
+func (cn *sqlConnector) Driver() driver.Driver {
+	if cn.driver != nil {
+		return cn.driver
+	}
+	return &sqlDriver{base: cn.base.Driver(), rec: cn.rec}
+}
+
+type sqlConn struct {
+	base driver.Conn
+	rec  *sqlRecorder
+}
+
+func (c *sqlConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *sqlConn) PrepareContext(ctx context.Context, query string) (stmt driver.Stmt, err error) {
+	start := time.Now()
+	if cp, ok := c.base.(driver.ConnPrepareContext); ok {
+		stmt, err = cp.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.base.Prepare(query)
+	}
+	c.rec.record(ctx, "prepare", start, err)
+	if err != nil {
+		return nil, err
+	}
+	s := &sqlStmt{base: stmt, conn: c.base, rec: c.rec}
+	if _, ok := stmt.(driver.ColumnConverter); ok {
+		return &sqlConverterStmt{s}, nil
+	}
+	return s, nil
+}
+
+func (c *sqlConn) Close() error {
+	return c.base.Close()
+}
+
+func (c *sqlConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *sqlConn) BeginTx(ctx context.Context, opts driver.TxOptions) (tx driver.Tx, err error) {
+	start := time.Now()
+	if cb, ok := c.base.(driver.ConnBeginTx); ok {
+		tx, err = cb.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.base.Begin()
+	}
+	c.rec.record(ctx, "begin", start, err)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlTx{base: tx, rec: c.rec, ctx: ctx}, nil
+}
+
+func (c *sqlConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.base.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	result, err := execer.ExecContext(ctx, query, args)
+	c.rec.record(ctx, "exec", start, err)
+	return result, err
+}
+
+func (c *sqlConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.base.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	c.rec.record(ctx, "query", start, err)
+	return rows, err
+}
+
+func (c *sqlConn) Ping(ctx context.Context) error {
+	if p, ok := c.base.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *sqlConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.base.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *sqlConn) IsValid() bool {
+	if v, ok := c.base.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *sqlConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.base.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type sqlStmt struct {
+	base driver.Stmt
+	conn driver.Conn
+	rec  *sqlRecorder
+}
+
+func (s *sqlStmt) Close() error  { return s.base.Close() }
+func (s *sqlStmt) NumInput() int { return s.base.NumInput() }
+
+// CheckNamedValue 语句实现了 NamedValueChecker 时优先使用, 否则使用连接的
+func (s *sqlStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := s.base.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	if checker, ok := s.conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// sqlConverterStmt 语句实现了 ColumnConverter 时使用, 未实现时不能暴露该接口
+type sqlConverterStmt struct {
+	*sqlStmt
+}
+
+func (s *sqlConverterStmt) ColumnConverter(idx int) driver.ValueConverter {
+	return s.base.(driver.ColumnConverter).ColumnConverter(idx)
+}
+
+func (s *sqlStmt) Exec(args []driver.Value) (driver.Result, error) {
+	start := time.Now()
+	result, err := s.base.Exec(args)
+	s.rec.record(context.Background(), "exec", start, err)
+	return result, err
+}
+
+func (s *sqlStmt) Query(args []driver.Value) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := s.base.Query(args)
+	s.rec.record(context.Background(), "query", start, err)
+	return rows, err
+}
+
+func (s *sqlStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var result driver.Result
+	var err error
+	if execer, ok := s.base.(driver.StmtExecContext); ok {
+		result, err = execer.ExecContext(ctx, args)
+	} else {
+		values, verr := namedValues(args)
+		if verr != nil {
+			return nil, verr
+		}
+		result, err = s.base.Exec(values)
+	}
+	s.rec.record(ctx, "exec", start, err)
+	return result, err
+}
+
+func (s *sqlStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if queryer, ok := s.base.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		values, verr := namedValues(args)
+		if verr != nil {
+			return nil, verr
+		}
+		rows, err = s.base.Query(values)
+	}
+	s.rec.record(ctx, "query", start, err)
+	return rows, err
+}
+
+// namedValues 旧驱动不支持命名参数
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("sql: driver does not support the use of Named Parameters")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
+
+type sqlTx struct {
+	base driver.Tx
+	rec  *sqlRecorder
+	ctx  context.Context // BeginTx 时的 ctx, 用于获取标签
+}
+
+func (tx *sqlTx) Commit() error {
+	start := time.Now()
+	err := tx.base.Commit()
+	tx.rec.record(tx.ctx, "commit", start, err)
+	return err
+}
+
+func (tx *sqlTx) Rollback() error {
+	start := time.Now()
+	err := tx.base.Rollback()
+	tx.rec.record(tx.ctx, "rollback", start, err)
+	return err
+}
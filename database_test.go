@@ -0,0 +1,150 @@
+package monitor_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"code.gopub.tech/commons/assert"
+	"code.gopub.tech/monitor"
+)
+
+// fakeDriver 内存中的假驱动, 执行 "fail" 语句时返回错误
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConnector struct{}
+
+func (fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{}, nil }
+func (fakeConnector) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query: query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+type fakeStmt struct{ query string }
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	if s.query == "fail" {
+		return nil, errors.New("fail")
+	}
+	return driver.RowsAffected(1), nil
+}
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) { return &fakeRows{}, nil }
+
+type fakeRows struct{ done bool }
+
+func (*fakeRows) Columns() []string { return []string{"v"} }
+func (*fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+func TestDatabase(t *testing.T) {
+	c := monitor.NewClient()
+	db := sql.OpenDB(c.WrapConnector(fakeConnector{}, "main"))
+	defer db.Close()
+	c.RegisterDBStats(db, "main")
+
+	ctx := monitor.CtxAddLabels(ctx, "biz", "test")
+	_, err := db.ExecContext(ctx, "insert")
+	assert.True(t, err == nil)
+	_, err = db.ExecContext(ctx, "fail")
+	assert.True(t, err != nil)
+	var v int
+	assert.True(t, db.QueryRowContext(ctx, "select").Scan(&v) == nil)
+	assert.True(t, v == 1)
+	tx, err := db.BeginTx(ctx, nil)
+	assert.True(t, err == nil)
+	assert.True(t, tx.Commit() == nil)
+
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	t.Log(body)
+	assert.True(t, strings.Contains(body, `timer:sql_seconds_count{biz="test",db="main",operation="exec",status="ok"} 1`))
+	assert.True(t, strings.Contains(body, `timer:sql_seconds_count{biz="test",db="main",operation="exec",status="error"} 1`))
+	assert.True(t, strings.Contains(body, `timer:sql_seconds_count{biz="test",db="main",operation="query",status="ok"} 1`))
+	assert.True(t, strings.Contains(body, `timer:sql_seconds_count{biz="test",db="main",operation="commit",status="ok"} 1`))
+	assert.True(t, strings.Contains(body, `go_sql_open_connections{db_name="main"} 1`))
+}
+
+func TestWrapDriver(t *testing.T) {
+	c := monitor.NewClient()
+	sql.Register("monitor-fake", c.WrapDriver(fakeDriver{}, "fake"))
+	db, err := sql.Open("monitor-fake", "")
+	assert.True(t, err == nil)
+	defer db.Close()
+	_, err = db.Exec("insert")
+	assert.True(t, err == nil)
+
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.True(t, strings.Contains(w.Body.String(), `timer:sql_seconds_count{db="fake",operation="exec",status="ok"} 1`))
+}
+
+// celsius 只有 converterStmt 能转换的参数类型
+type celsius struct{ v float64 }
+
+// converterConn 语句实现了 NamedValueChecker 及 ColumnConverter 的连接
+type converterConn struct{ fakeConn }
+
+func (converterConn) Prepare(query string) (driver.Stmt, error) {
+	return converterStmt{fakeStmt{query: query}}, nil
+}
+
+type converterStmt struct{ fakeStmt }
+
+func (converterStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if v, ok := nv.Value.(celsius); ok {
+		nv.Value = v.v
+		return nil
+	}
+	return driver.ErrSkip
+}
+
+func (converterStmt) ColumnConverter(int) driver.ValueConverter {
+	return driver.ValueConverter(driver.String)
+}
+
+func (s converterStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if len(args) != 2 || args[0] != 36.5 || args[1] != "1" {
+		return nil, errors.New("unexpected args")
+	}
+	return s.fakeStmt.Exec(args)
+}
+
+type converterConnector struct{ fakeConnector }
+
+func (converterConnector) Connect(context.Context) (driver.Conn, error) { return converterConn{}, nil }
+
+func TestDatabaseStmtConverter(t *testing.T) {
+	c := monitor.NewClient()
+	db := sql.OpenDB(c.WrapConnector(converterConnector{}, "main"))
+	defer db.Close()
+	stmt, err := db.Prepare("insert")
+	assert.True(t, err == nil, err)
+	defer stmt.Close()
+	_, err = stmt.Exec(celsius{36.5}, 1)
+	assert.True(t, err == nil, err)
+}
@@ -10,7 +10,17 @@ package monitor provides a simple way to monitor your application.
 
 	WithNamespace("namespace")	// 默认值为空
 	WithSubsystem("subsystem")	// 默认值为空
-	// 指标类型不同, 默认值不同
+	// 命名方式, 默认值是 NamingColon; NamingUnderscore 为 namespace_subsystem_name 格式, NamingDual 同时写入两种指标名
+	WithNamingScheme(monitor.NamingUnderscore)
+	// 指标名/标签名使用 UTF-8 原样输出(默认使用 EscapeName 转义), 不支持 UTF-8 的抓取方按指定方式转义
+	// 需要先调用 monitor.EnableUTF8Names()
+	WithUTF8Names(monitor.EscapingValues)
+	// 耗时的单位, 默认值是 time.Second; 使用毫秒时 Timer 的默认后缀是 `_ms`, 默认分布也按毫秒换算
+	WithTimeUnit(time.Millisecond)
+	// 异步模式, 打点只放入队列, 由后台 goroutine 合并后批量写入; 队列已满时按策略丢弃并记录到内部异常指标
+	// 需要调用 Close 停止, 测试中读取指标前调用 Flush
+	WithAsync(monitor.AsyncQueueSize(1<<14), monitor.AsyncDropPolicy(monitor.DropOldest))
+	// 指标类型不同, 默认值不同(以下为 NamingColon 的默认值, NamingUnderscore 时没有前缀, Counter 后缀默认值是 `_total`)
 	// Counter 指标, 前缀默认值是 `counter:`
 	// Gauge 指标, 前缀默认值是 `gauge:`
 	// Timer 指标, 前缀默认值是 `timer:`, 后缀默认值是 `_seconds`
@@ -25,14 +35,73 @@ package monitor provides a simple way to monitor your application.
 	// .25/250ms, .5/500ms, 1/1s, 2.5/2.5s, 5/5s, 10/10s.
 	WithBuckets([]float64{})
 	WithObjectives(map[float64]float64{})
+	// 默认标签, 每次打点都会附加, 可被 ctx 及调用时传入的标签覆盖
+	WithLabels("k", "v")
+	// 默认值是 PrometheusSink, 即写入 registry
+	WithSinks(monitor.PrometheusSink, mySink)
+	// 定时将指标摘要输出到日志, 需要调用 Close 停止
+	WithLogDump(slog.Default(), time.Minute)
+	// 定时(及 Close 时)将指标写入文件, 供 node_exporter 的 textfile collector 读取
+	WithTextfile("/path/to/app.prom", time.Minute)
+	// 读取目录下的 *.prom 文件, 合并到 registry 中暴露
+	WithTextfileCollector("/path/to/dir")
+	// 注册 go_*, process_*, 构建信息等标准指标(默认不注册)
+	WithGoCollector(collectors.MetricsGC)
+	WithProcessCollector()
+	WithBuildInfo()
+	// 按指标名覆盖默认分布/分位数, 启用/禁用指标; 指标名支持 glob 及 re: 开头的正则表达式
+	WithMetricBuckets("db_*", .001, .01, .1)
+	WithMetricBuckets("re:^batch_", 60, 300, 1800)
+	WithMetricObjectives("db_query", map[float64]float64{0.99: 0.001})
+	WithDisabledMetrics("re:_debug$")
+	// 打点时的分布/分位数与已注册的不一致时, 记录到追加了哈希后缀的另一个指标中(默认仍记录到已注册的指标)
+	WithMismatchStrategy(monitor.MismatchSibling)
+	// 拒绝与已注册指标的类型/说明/标签名不一致的打点(默认只输出双方的调用位置)
+	WithStrictSchema(true)
+	// 按 prometheus 的命名规范检查指标名, 不符合时输出日志; LintFix 自动修正(如补充 _total 后缀)
+	WithNameLint(monitor.LintIgnore(monitor.RuleColon), monitor.LintFix())
+	// 监听配置文件, 修改后自动调用 Reconfigure
+	WithReconfigureFile("/path/to/monitor.yaml", time.Minute)
+	// 从环境变量(如 MONITOR_NAMESPACE, MONITOR_BUCKETS)读取配置, 覆盖之前的选项
+	WithEnv("MONITOR")
+
+也可以从 YAML/JSON 配置文件初始化, 参见 Config:
+
+	c, err := monitor.NewClientFromConfig(f, monitor.WithEnv("MONITOR"))
+
+分布, 分位数及启用/禁用的指标可以在运行时修改, 使用默认值的指标会按新的配置重建:
+
+	rebuilt, err := c.Reconfigure(monitor.WithBuckets([]float64{.01, .1, 1}))
 
 应用程序使用 Record(ctx, "name", "help") 等 API 进行打点, 
 指标名会自动拼接前缀/后缀, 然后再附加上名称空间/子模块, 最终格式为:
 
 	<namespace>:<subsystem>:<prefix><name><suffix>{<labels>}
 
+使用 WithNamingScheme(monitor.NamingUnderscore) 时为 prometheus 推荐的格式(冒号保留给 recording rules 使用):
+
+	<namespace>_<subsystem>_<name>_total{<labels>}   // Counter
+	<namespace>_<subsystem>_<name>_seconds{<labels>} // Timer
+	<namespace>_<subsystem>_<name>{<labels>}         // Gauge, Histogram, Summary
+
+全局 client 可以替换为使用新命名方式的 client, 迁移期间可以使用 NamingDual 同时写入新旧两种指标名:
+
+	monitor.SetDefault(monitor.NewClient(monitor.WithNamingScheme(monitor.NamingDual)))
+
 默认会在 /metrics 端点暴露打点数据.
 也可以通过 HTTPHandler() 获取 handler 自行注册到不同的路径.
+也可以通过 Serve 启动独立的指标服务(支持 pprof, 健康检查, TLS 及 unix socket):
+
+	go monitor.Default().Serve(ctx, ":9100", monitor.ServePprof())
+
+Catalog 列出通过打点 API 注册的所有指标(类型, 标签, 分布, 首次注册的调用位置, 时间序列数等), 用于审查打点情况:
+
+	http.Handle("/metrics/catalog", c.CatalogHandler()) // HTML, 或 ?format=json
+
+多个 client (各自使用独立的 registry) 可以合并到一个 handler 中暴露, 可选为每个来源添加区分标签:
+
+	http.Handle("/metrics", monitor.MultiHandler(c1, c2))
+	http.Handle("/metrics", monitor.MultiHandlerFor("component", c1.Source("api"), c2.Source("worker")))
 
 # API 使用
 
@@ -46,6 +115,7 @@ Gauge 类型指标用于存储当前瞬时值, 可以增加, 减少, 重置.
 指标名默认会拼接 `gauge:` 前缀.
 
 	monitor.Store(ctx, name, help, value)
+	monitor.Add(ctx, name, help, delta)
 
 记录耗时, 使用 Cost, CostBuckets, 或 Timer/Observe  方法.
 指标名默认会拼接 `timer:` 前缀, `_seconds` 后缀.
@@ -60,18 +130,61 @@ Gauge 类型指标用于存储当前瞬时值, 可以增加, 减少, 重置.
 	// 使用 Observe 方法配合 defer 使用, 会自动记录耗时
 	// Observe 方法底层使用 Summary 类型
 	defer monitor.Observe()(ctx, name, help)
+	// TimerOutcome/ObserveOutcome 额外接收 *error, 自动添加 outcome 标签
+	// outcome=success|error|canceled|deadline_exceeded
+	defer monitor.TimerOutcome()(ctx, &err, name, help)
+	// Track 跟踪处理中的操作, 抓取时可以看到处理中的数量及最早的操作已持续的时间
+	defer monitor.Track(ctx, name, help)()
+	// StartSpan 记录嵌套阶段耗时, 使用 span/parent 标签区分各阶段
+	ctx, end := monitor.StartSpan(ctx, "phase")
+
+记录函数调用的耗时及结果(status=ok|panic|错误分类), 错误分类可通过 WithErrorClassifier 自定义
+
+	err := monitor.Do(ctx, c, name, help, func(ctx context.Context) error { ... })
+	fn = monitor.Wrap(c, name, help, fn)
 
 记录直方图
 指标名默认会拼接 `histogram:` 前缀.
 
 	monitor.Histogram(ctx, name, help, value, buckets)
 
+按单位记录字节数, 比例/百分比, 温度等, 会换算为基本单位并追加单位后缀(如 _bytes, _ratio, _celsius)
+
+	monitor.StoreUnit(ctx, "heap", help, 512, monitor.Mebibytes)   // gauge:heap_bytes 536870912
+	monitor.StoreUnit(ctx, "cpu_usage", help, 85, monitor.Percent) // gauge:cpu_usage_ratio 0.85
+	monitor.RecordUnit(ctx, "sent", help, n, monitor.Bytes)        // counter:sent_bytes
+	monitor.HistogramUnit(ctx, "body", help, n, monitor.Kibibytes, buckets)
+
 记录摘要
 指标名默认会拼接 `summary:` 前缀.
 
 	monitor.Summary(ctx, name, help, value)
 	monitor.SummaryObjectives(ctx, name, help, value, objectives)
 
+HTTP 服务端中间件, 记录请求数, 耗时, 请求/响应大小, 处理中的请求数
+
+	http.ListenAndServe(":8080", monitor.HTTPMiddleware(mux))
+
+HTTP 客户端, 记录出站请求数, 耗时及各阶段(DNS, 连接, TLS, 首字节)耗时
+
+	hc := &http.Client{Transport: monitor.Default().RoundTripper(nil)}
+
+数据库, 记录连接池指标及 SQL 操作耗时
+
+	db := sql.OpenDB(monitor.Default().WrapConnector(connector, "main"))
+	monitor.Default().RegisterDBStats(db, "main")
+
+# 子 client
+
+组件/库可以使用派生的子 client, 与父 client 共享 registry, 子系统追加组件名, 避免指标名冲突.
+
+	db := monitor.Default().Sub("db")
+	// namespace:subsystem:db:counter:query_total
+	// NamingUnderscore 时为 namespace_subsystem_db_query_total
+	db.Record(ctx, "query_total", "查询次数")
+	// 修改默认标签的标签名时需要同时修改子系统, 避免同名指标的标签名不一致
+	cache := monitor.Default().Sub("cache", monitor.WithLabels("component", "cache"))
+
 # 标签 Labels
 
 每个 API 都可选传入标签(labels), 
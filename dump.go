@@ -0,0 +1,244 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// WithLogDump 每隔 interval 通过 logger 输出一次指标摘要, 调用 Close 时停止
+// 适用于只有日志采集, 没有 prometheus 抓取的场景. interval 不是正数时通过 client 的 logger 输出错误, 不会启动.
+//
+//	c := monitor.NewClient(monitor.WithLogDump(slog.Default(), time.Minute,
+//		monitor.DumpFilter(func(name string) bool { return strings.HasPrefix(name, "counter:") }),
+//		monitor.DumpMaxRecords(50),
+//	))
+//	defer c.Close()
+func WithLogDump(logger *slog.Logger, interval time.Duration, opts ...DumpOpt) Opt {
+	return func(c *client) {
+		c.starters = append(c.starters, func(c *client) {
+			if interval <= 0 {
+				c.logger(context.Background(), "log_dump|InvalidInterval", "interval", interval)
+				return
+			}
+			d := NewLogDumper(c.registry, logger, opts...)
+			c.life.goBackground(func(done <-chan struct{}) {
+				ticker := time.NewTicker(interval)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-done:
+						return
+					case <-ticker.C:
+						d.Dump(context.Background())
+					}
+				}
+			})
+		})
+	}
+}
+
+// LogDumper 将指标摘要输出到日志
+//
+// 每条时间序列输出一条日志:
+// counter 输出自上次 Dump 以来的增量(增量为 0 的不输出);
+// gauge 输出当前值;
+// histogram 输出 count, sum 以及根据桶分布估算的分位数;
+// summary 输出 count, sum 以及客户端计算的分位数.
+type LogDumper struct {
+	gatherer   prometheus.Gatherer
+	logger     *slog.Logger
+	level      slog.Level
+	filter     func(name string) bool
+	maxRecords int
+	quantiles  []float64
+	mu         sync.Mutex
+	prev       map[string]float64 // 上次 Dump 时 counter 的值
+}
+
+// DumpOpt LogDumper 的配置项
+type DumpOpt func(*LogDumper)
+
+// DumpLevel 设置日志级别
+// 默认值是 [slog.LevelInfo]
+func DumpLevel(level slog.Level) DumpOpt {
+	return func(d *LogDumper) {
+		d.level = level
+	}
+}
+
+// DumpFilter 按指标名过滤, 返回 true 的指标才会输出
+// 默认值是 nil, 表示输出所有指标
+func DumpFilter(filter func(name string) bool) DumpOpt {
+	return func(d *LogDumper) {
+		d.filter = filter
+	}
+}
+
+// DumpMaxRecords 限制每次 Dump 最多输出的日志条数, 超出部分只输出一条汇总日志
+// 默认值是 100, 小于等于 0 表示不限制
+func DumpMaxRecords(n int) DumpOpt {
+	return func(d *LogDumper) {
+		d.maxRecords = n
+	}
+}
+
+// DumpQuantiles 设置 histogram 估算的分位数
+// 默认值是 0.5, 0.9, 0.99
+func DumpQuantiles(quantiles ...float64) DumpOpt {
+	return func(d *LogDumper) {
+		d.quantiles = quantiles
+	}
+}
+
+// NewLogDumper 新建 LogDumper
+func NewLogDumper(gatherer prometheus.Gatherer, logger *slog.Logger, opts ...DumpOpt) *LogDumper {
+	d := &LogDumper{
+		gatherer:   gatherer,
+		logger:     logger,
+		level:      slog.LevelInfo,
+		maxRecords: 100,
+		quantiles:  []float64{.5, .9, .99},
+		prev:       map[string]float64{},
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.logger == nil {
+		d.logger = slog.Default()
+	}
+	return d
+}
+
+// Dump 输出一次指标摘要
+func (d *LogDumper) Dump(ctx context.Context) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	families, err := d.gatherer.Gather()
+	if err != nil {
+		d.logger.Log(ctx, slog.LevelWarn, "metric_dump|GatherFailed", "err", err)
+	}
+	var records, omitted int
+	current := map[string]float64{}
+	for _, mf := range families {
+		name := mf.GetName()
+		if d.filter != nil && !d.filter(name) {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			labels := labelString(m.GetLabel())
+			key := name + "{" + labels + "}"
+			if prev, ok := d.prev[key]; ok {
+				current[key] = prev // 未输出的计数器保留上次输出时的值, 下次输出完整的增量
+			}
+			attrs, ok := d.attrs(mf.GetType(), name, labels, key, m)
+			if !ok {
+				continue
+			}
+			if d.maxRecords > 0 && records >= d.maxRecords {
+				omitted++
+				continue
+			}
+			records++
+			if mf.GetType() == dto.MetricType_COUNTER {
+				current[key] = m.GetCounter().GetValue()
+			}
+			d.logger.LogAttrs(ctx, d.level, "metric_dump", attrs...)
+		}
+	}
+	if omitted > 0 {
+		d.logger.LogAttrs(ctx, d.level, "metric_dump_truncated",
+			slog.Int("records", records), slog.Int("omitted", omitted))
+	}
+	d.prev = current
+}
+
+func (d *LogDumper) attrs(
+	typ dto.MetricType, name, labels, key string, m *dto.Metric,
+) ([]slog.Attr, bool) {
+	attrs := []slog.Attr{
+		slog.String("type", strings.ToLower(typ.String())),
+		slog.String("name", name),
+		slog.String("labels", labels),
+	}
+	switch typ {
+	case dto.MetricType_COUNTER:
+		value := m.GetCounter().GetValue()
+		delta := value - d.prev[key]
+		if delta == 0 {
+			return nil, false
+		}
+		return append(attrs, slog.Float64("delta", delta), slog.Float64("total", value)), true
+	case dto.MetricType_GAUGE:
+		return append(attrs, slog.Float64("value", m.GetGauge().GetValue())), true
+	case dto.MetricType_HISTOGRAM:
+		h := m.GetHistogram()
+		attrs = append(attrs, slog.Uint64("count", h.GetSampleCount()), slog.Float64("sum", h.GetSampleSum()))
+		for _, q := range d.quantiles {
+			attrs = append(attrs, slog.Float64(quantileKey(q), bucketQuantile(q, h)))
+		}
+		return attrs, true
+	case dto.MetricType_SUMMARY:
+		s := m.GetSummary()
+		attrs = append(attrs, slog.Uint64("count", s.GetSampleCount()), slog.Float64("sum", s.GetSampleSum()))
+		for _, q := range s.GetQuantile() {
+			attrs = append(attrs, slog.Float64(quantileKey(q.GetQuantile()), q.GetValue()))
+		}
+		return attrs, true
+	default:
+		return append(attrs, slog.Float64("value", m.GetUntyped().GetValue())), true
+	}
+}
+
+// labelString 将标签格式化为 k1=v1,k2=v2
+func labelString(pairs []*dto.LabelPair) string {
+	var sb strings.Builder
+	for i, p := range pairs {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(p.GetName())
+		sb.WriteByte('=')
+		sb.WriteString(p.GetValue())
+	}
+	return sb.String()
+}
+
+// quantileKey 0.99 => p99
+func quantileKey(q float64) string {
+	return "p" + strconv.FormatFloat(q*100, 'g', 6, 64)
+}
+
+// bucketQuantile 根据桶分布估算分位数, 算法同 PromQL 的 histogram_quantile:
+// 找到分位数所在的桶, 在桶内线性插值.
+func bucketQuantile(q float64, h *dto.Histogram) float64 {
+	count := float64(h.GetSampleCount())
+	buckets := h.GetBucket()
+	if count == 0 || len(buckets) == 0 {
+		return math.NaN()
+	}
+	rank := q * count
+	var prevBound, prevCount float64
+	for i, b := range buckets {
+		bound, cumulative := b.GetUpperBound(), float64(b.GetCumulativeCount())
+		if cumulative >= rank {
+			if math.IsInf(bound, +1) {
+				return prevBound
+			}
+			if cumulative == prevCount || (i == 0 && bound <= 0) {
+				return bound
+			}
+			return prevBound + (bound-prevBound)*(rank-prevCount)/(cumulative-prevCount)
+		}
+		prevBound, prevCount = bound, cumulative
+	}
+	// 落在 +Inf 桶中, 返回最大的有限上界
+	return prevBound
+}
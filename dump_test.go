@@ -0,0 +1,96 @@
+package monitor_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"code.gopub.tech/commons/assert"
+	"code.gopub.tech/monitor"
+)
+
+func TestLogDumper(t *testing.T) {
+	c := monitor.NewClient()
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	d := monitor.NewLogDumper(c.Registry(), logger,
+		monitor.DumpFilter(func(name string) bool { return !strings.Contains(name, "skip") }),
+	)
+
+	c.RecordN(ctx, "xxx_throughput", "xxx总量", 3)
+	c.Store(ctx, "xxx_current_value", "xxx当前值", 7)
+	c.Store(ctx, "skip_value", "不输出", 1)
+	for i := 0; i < 100; i++ {
+		c.Histogram(ctx, "xxx_value", "xxx分布", i, []float64{25, 50, 75, 100})
+	}
+	d.Dump(ctx)
+	out := buf.String()
+	t.Log(out)
+	assert.True(t, strings.Contains(out, "name=counter:xxx_throughput labels=\"\" delta=3 total=3"))
+	assert.True(t, strings.Contains(out, "name=gauge:xxx_current_value labels=\"\" value=7"))
+	assert.True(t, strings.Contains(out, "count=100 sum=4950 p50=49 "))
+	assert.True(t, !strings.Contains(out, "skip_value"))
+
+	// counter 输出增量, 无变化的不输出
+	buf.Reset()
+	c.RecordN(ctx, "xxx_throughput", "xxx总量", 2)
+	d.Dump(ctx)
+	out = buf.String()
+	assert.True(t, strings.Contains(out, "delta=2 total=5"))
+
+	buf.Reset()
+	d.Dump(ctx)
+	assert.True(t, !strings.Contains(buf.String(), "xxx_throughput"))
+}
+
+func TestLogDumperMaxRecords(t *testing.T) {
+	c := monitor.NewClient()
+	var buf bytes.Buffer
+	d := monitor.NewLogDumper(c.Registry(), slog.New(slog.NewTextHandler(&buf, nil)), monitor.DumpMaxRecords(1))
+	c.Store(ctx, "a", "a", 1)
+	c.Store(ctx, "b", "b", 1)
+	c.Store(ctx, "c", "c", 1)
+	d.Dump(ctx)
+	assert.True(t, strings.Count(buf.String(), "msg=metric_dump ") == 1)
+	assert.True(t, strings.Contains(buf.String(), "msg=metric_dump_truncated records=1 omitted=2"))
+}
+
+func TestLogDumperOmittedDelta(t *testing.T) {
+	c := monitor.NewClient()
+	var buf bytes.Buffer
+	d := monitor.NewLogDumper(c.Registry(), slog.New(slog.NewTextHandler(&buf, nil)),
+		monitor.DumpMaxRecords(1), monitor.DumpFilter(func(name string) bool { return strings.HasPrefix(name, "counter:") }))
+	c.Record(ctx, "a", "a")
+	c.Record(ctx, "b", "b")
+	d.Dump(ctx)
+	assert.True(t, strings.Contains(buf.String(), "name=counter:a labels=\"\" delta=1"), buf.String())
+	assert.True(t, !strings.Contains(buf.String(), "name=counter:b"))
+
+	// 上次被截断的计数器, 增量从上次输出时算起
+	buf.Reset()
+	d.Dump(ctx)
+	assert.True(t, strings.Contains(buf.String(), "name=counter:b labels=\"\" delta=1 total=1"), buf.String())
+}
+
+func TestWithLogDump(t *testing.T) {
+	var buf bytes.Buffer
+	c := monitor.NewClient(monitor.WithLogDump(slog.New(slog.NewTextHandler(&buf, nil)), time.Millisecond*10))
+	c.Record(ctx, "xxx_throughput", "xxx总量")
+	time.Sleep(time.Millisecond * 30)
+	assert.True(t, c.Close() == nil)
+	assert.True(t, c.Close() == nil)
+	assert.True(t, strings.Contains(buf.String(), "counter:xxx_throughput"))
+}
+
+func TestWithLogDumpInvalidInterval(t *testing.T) {
+	var logs []string
+	c := monitor.NewClient(
+		monitor.WithLogDump(slog.Default(), 0),
+		monitor.WithLogger(func(_ context.Context, msg string, _ ...any) { logs = append(logs, msg) }),
+	)
+	assert.True(t, c.Close() == nil)
+	assert.DeepEqual(t, logs, []string{"log_dump|InvalidInterval"})
+}
@@ -35,10 +35,17 @@ func Default() *client {
 }
 
 // SetDefault 设置全局默认的 client
+//
+//	monitor.SetDefault(monitor.NewClient(monitor.WithNamingScheme(monitor.NamingUnderscore)))
 func SetDefault(d *client) {
 	defaultClient = d
 }
 
+// Flush 全局 client 为异步模式时, 等待之前的打点全部写入, 参见 WithAsync
+func Flush() {
+	defaultClient.Flush()
+}
+
 // Record 记录打点 累加计数器 +1
 func Record(ctx context.Context, name, desc string, kvs ...string) {
 	defaultClient.Record(ctx, name, desc, kvs...)
@@ -54,6 +61,11 @@ func Store(ctx context.Context, name, desc string, value nums.AnyNumber, kvs ...
 	defaultClient.Store(ctx, name, desc, value, kvs...)
 }
 
+// Add 在瞬时值上累加 delta
+func Add(ctx context.Context, name, desc string, delta nums.AnyNumber, kvs ...string) {
+	defaultClient.Add(ctx, name, desc, delta, kvs...)
+}
+
 // Cost 记录耗时(使用 Timer 指标前缀/后缀)
 func Cost(ctx context.Context, name, desc string, cost time.Duration, kvs ...string) {
 	defaultClient.Cost(ctx, name, desc, cost, kvs...)
@@ -69,16 +81,51 @@ func Timer() func(ctx context.Context, name, desc string, kvs ...string) time.Du
 	return defaultClient.Timer()
 }
 
+// TimerOutcome 记录耗时及结果(使用 Timer 指标前缀/后缀)
+func TimerOutcome(buckets ...float64) func(ctx context.Context, errp *error, name, desc string, kvs ...string) time.Duration {
+	return defaultClient.TimerOutcome(buckets...)
+}
+
+// Track 跟踪一个处理中的操作, 返回的函数在操作结束时调用
+func Track(ctx context.Context, name, desc string, kvs ...string) func() time.Duration {
+	return defaultClient.Track(ctx, name, desc, kvs...)
+}
+
+// StartSpan 开始一个计时阶段, 返回带有该阶段的 ctx 及结束函数
+func StartSpan(ctx context.Context, name string, kvs ...string) (context.Context, func() time.Duration) {
+	return defaultClient.StartSpan(ctx, name, kvs...)
+}
+
 // Histogram 记录值的分布. 如果无需记录分布, 请使用 Summary
 func Histogram(ctx context.Context, name, desc string, value nums.AnyNumber, buckets []float64, kvs ...string) {
 	defaultClient.Histogram(ctx, name, desc, value, buckets, kvs...)
 }
 
+// RecordUnit 按单位累加计数器, 换算为基本单位并追加单位后缀
+func RecordUnit(ctx context.Context, name, desc string, value nums.AnyNumber, unit Unit, kvs ...string) {
+	defaultClient.RecordUnit(ctx, name, desc, value, unit, kvs...)
+}
+
+// StoreUnit 按单位存储当前瞬时值, 换算为基本单位并追加单位后缀
+func StoreUnit(ctx context.Context, name, desc string, value nums.AnyNumber, unit Unit, kvs ...string) {
+	defaultClient.StoreUnit(ctx, name, desc, value, unit, kvs...)
+}
+
+// HistogramUnit 按单位记录值的分布, 换算为基本单位并追加单位后缀
+func HistogramUnit(ctx context.Context, name, desc string, value nums.AnyNumber, unit Unit, buckets []float64, kvs ...string) {
+	defaultClient.HistogramUnit(ctx, name, desc, value, unit, buckets, kvs...)
+}
+
 // Observe 记录耗时摘要(使用 Timer 指标前缀/后缀)
 func Observe() func(ctx context.Context, name, desc string, kvs ...string) time.Duration {
 	return defaultClient.Observe()
 }
 
+// ObserveOutcome 记录耗时摘要及结果(使用 Timer 指标前缀/后缀)
+func ObserveOutcome() func(ctx context.Context, errp *error, name, desc string, kvs ...string) time.Duration {
+	return defaultClient.ObserveOutcome()
+}
+
 // Summary 记录摘要(使用 Summary 指标前缀/后缀)
 func Summary(ctx context.Context, name, desc string, value nums.AnyNumber, kvs ...string) {
 	defaultClient.Summary(ctx, name, desc, value, kvs...)
@@ -88,3 +135,8 @@ func Summary(ctx context.Context, name, desc string, value nums.AnyNumber, kvs .
 func SummaryObjectives(ctx context.Context, name, desc string, value nums.AnyNumber, objectives map[float64]float64, kvs ...string) {
 	defaultClient.SummaryObjectives(ctx, name, desc, value, objectives, kvs...)
 }
+
+// HTTPMiddleware 返回记录 HTTP 请求指标的中间件
+func HTTPMiddleware(next http.Handler, opts ...HTTPOpt) http.Handler {
+	return defaultClient.HTTPMiddleware(next, opts...)
+}
@@ -0,0 +1,83 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"time"
+)
+
+// Do 执行 fn, 并记录调用耗时及结果(使用 Timer 指标前缀/后缀)
+//
+// 所有结果记录在同一个指标中, 通过 status 标签区分:
+// ok 表示成功, panic 表示发生了 panic(记录后会继续 panic), 其他值为错误分类(参见 WithErrorClassifier).
+// 指标的 _count 即为调用次数.
+//
+//	// namespace:subsystem:timer:load_user_seconds_count{status="ok"}
+//	// namespace:subsystem:timer:load_user_seconds_count{status="timeout"}
+//	err := monitor.Do(ctx, c, "load_user", "加载用户", func(ctx context.Context) error {
+//		return loadUser(ctx, id)
+//	})
+func Do(ctx context.Context, c *client, name, desc string, fn func(context.Context) error, kvs ...string) (err error) {
+	start := time.Now()
+	status := "panic"
+	defer func() {
+		c.recordHistogram(ctx, name, desc, timerNames, c.timeValue(time.Since(start)), nil, append(kvs[:len(kvs):len(kvs)], "status", status)...)
+	}()
+	err = fn(ctx)
+	status = "ok"
+	if err != nil {
+		status = c.classify(err)
+	}
+	return err
+}
+
+// Wrap 包装 fn, 返回的函数每次调用都会记录耗时及结果, 参见 Do
+//
+//	loadUser = monitor.Wrap(c, "load_user", "加载用户", loadUser)
+//	user, err := loadUser(ctx, id)
+func Wrap[T, R any](c *client, name, desc string, fn func(context.Context, T) (R, error), kvs ...string) func(context.Context, T) (R, error) {
+	return func(ctx context.Context, arg T) (result R, err error) {
+		err = Do(ctx, c, name, desc, func(ctx context.Context) error {
+			result, err = fn(ctx, arg)
+			return err
+		}, kvs...)
+		return result, err
+	}
+}
+
+// ClassifyError 默认的错误分类函数
+//
+// 返回值为 ok(err 为 nil), canceled, timeout, connection_refused, connection_reset,
+// dns, tls, eof, 其他错误均为 error.
+func ClassifyError(err error) string {
+	var (
+		netErr  net.Error
+		dnsErr  *net.DNSError
+		certErr *tls.CertificateVerificationError
+		recErr  tls.RecordHeaderError
+	)
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "connection_refused"
+	case errors.Is(err, syscall.ECONNRESET):
+		return "connection_reset"
+	case errors.As(err, &dnsErr):
+		return "dns"
+	case errors.As(err, &certErr), errors.As(err, &recErr):
+		return "tls"
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "eof"
+	default:
+		return "error"
+	}
+}
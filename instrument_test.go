@@ -0,0 +1,66 @@
+package monitor_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"code.gopub.tech/commons/assert"
+	"code.gopub.tech/monitor"
+)
+
+var errNotFound = errors.New("not found")
+
+func TestDo(t *testing.T) {
+	c := monitor.NewClient(monitor.WithErrorClassifier(func(err error) string {
+		if errors.Is(err, errNotFound) {
+			return "not_found"
+		}
+		return monitor.ClassifyError(err)
+	}))
+	ok := func(context.Context) error { return nil }
+	assert.True(t, monitor.Do(ctx, c, "job", "任务", ok) == nil)
+	assert.True(t, monitor.Do(ctx, c, "job", "任务", func(context.Context) error { return errNotFound }) == errNotFound)
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	monitor.Do(canceled, c, "job", "任务", func(ctx context.Context) error { return ctx.Err() })
+	func() {
+		defer func() { assert.True(t, recover() == "boom") }()
+		monitor.Do(ctx, c, "job", "任务", func(context.Context) error { panic("boom") })
+	}()
+
+	loadUser := monitor.Wrap(c, "load_user", "加载用户", func(_ context.Context, id int) (string, error) {
+		if id == 0 {
+			return "", errNotFound
+		}
+		return "user", nil
+	})
+	user, err := loadUser(ctx, 1)
+	assert.True(t, user == "user" && err == nil)
+	_, err = loadUser(ctx, 0)
+	assert.True(t, err == errNotFound)
+
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	t.Log(body)
+	for _, s := range []string{
+		`timer:job_seconds_count{status="ok"} 1`,
+		`timer:job_seconds_count{status="not_found"} 1`,
+		`timer:job_seconds_count{status="canceled"} 1`,
+		`timer:job_seconds_count{status="panic"} 1`,
+		`timer:load_user_seconds_count{status="ok"} 1`,
+		`timer:load_user_seconds_count{status="not_found"} 1`,
+	} {
+		assert.True(t, strings.Contains(body, s), s)
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	assert.True(t, monitor.ClassifyError(nil) == "ok")
+	assert.True(t, monitor.ClassifyError(context.Canceled) == "canceled")
+	assert.True(t, monitor.ClassifyError(context.DeadlineExceeded) == "timeout")
+	assert.True(t, monitor.ClassifyError(errNotFound) == "error")
+}
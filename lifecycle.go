@@ -0,0 +1,50 @@
+package monitor
+
+import (
+	"errors"
+	"sync"
+)
+
+// lifecycle 管理 client 的后台任务
+type lifecycle struct {
+	once    sync.Once
+	done    chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	onClose []func() error
+}
+
+func newLifecycle() *lifecycle {
+	return &lifecycle{done: make(chan struct{})}
+}
+
+// goBackground 启动一个后台任务, done 被关闭时任务应当退出
+func (l *lifecycle) goBackground(task func(done <-chan struct{})) {
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		task(l.done)
+	}()
+}
+
+// addCloser 添加关闭时执行的函数, 在所有后台任务退出后按添加顺序执行
+func (l *lifecycle) addCloser(f func() error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onClose = append(l.onClose, f)
+}
+
+func (l *lifecycle) close() (err error) {
+	l.once.Do(func() {
+		close(l.done)
+		l.wg.Wait()
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		var errs []error
+		for _, f := range l.onClose {
+			errs = append(errs, f())
+		}
+		err = errors.Join(errs...)
+	})
+	return
+}
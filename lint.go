@@ -0,0 +1,259 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"code.gopub.tech/commons/choose"
+	"code.gopub.tech/commons/syncs"
+)
+
+// NameRule 指标命名规范的规则
+// 参见 https://prometheus.io/docs/practices/naming/
+type NameRule string
+
+const (
+	RuleCounterTotal   NameRule = "counter_total"   // counter 以 _total 结尾
+	RuleBaseUnit       NameRule = "base_unit"       // 使用基本单位, 如 _seconds 而不是 _milliseconds
+	RuleUnitMixing     NameRule = "unit_mixing"     // 不混用多个单位, 如 _seconds_bytes
+	RuleSnakeCase      NameRule = "snake_case"      // 使用小写字母, 数字及单个下划线
+	RuleReservedSuffix NameRule = "reserved_suffix" // 不使用 _bucket, _count, _sum 等保留后缀
+	RuleColon          NameRule = "colon"           // 冒号保留给 recording rules 使用
+)
+
+// NameViolation 一条不符合命名规范的问题
+type NameViolation struct {
+	Rule    NameRule
+	Message string
+}
+
+func (v NameViolation) String() string {
+	return string(v.Rule) + ": " + v.Message
+}
+
+// NameLint 一个指标名的检查结果
+type NameLint struct {
+	Name       string          // 完整指标名
+	Kind       Kind            // 指标类型
+	Violations []NameViolation // 不符合的规则
+	Fixed      string          // 开启 LintFix 时为修正后实际使用的指标名, 否则与 Name 相同
+}
+
+// baseUnits 基本单位
+var baseUnits = []string{"seconds", "bytes", "ratio", "celsius", "meters", "volts", "amperes", "joules", "grams"}
+
+// nonBaseUnits 非基本单位 => 对应的基本单位
+var nonBaseUnits = map[string]string{
+	"nanoseconds": "seconds", "ns": "seconds",
+	"microseconds": "seconds", "us": "seconds",
+	"milliseconds": "seconds", "millis": "seconds", "ms": "seconds",
+	"minutes": "seconds", "hours": "seconds", "days": "seconds",
+	"bits": "bytes", "kilobytes": "bytes", "kb": "bytes",
+	"megabytes": "bytes", "mb": "bytes", "gigabytes": "bytes", "gb": "bytes",
+	"percent": "ratio", "percentage": "ratio",
+	"fahrenheit": "celsius", "kelvin": "celsius",
+	"millimeters": "meters", "centimeters": "meters", "kilometers": "meters",
+	"kilograms": "grams",
+}
+
+var reservedSuffixes = []string{"_bucket", "_count", "_sum", "_created"}
+
+var snakeCasePattern = regexp.MustCompile(`^[a-z_][a-z0-9]*(_[a-z0-9]+)*$`)
+
+// LintName 按 prometheus 的命名规范检查完整指标名, 返回不符合的规则, 符合时返回 nil
+// ignore 指定忽略的规则.
+//
+//	monitor.LintName("timer:api_ms", monitor.KindHistogram)
+//	// [base_unit: ... colon: ...]
+func LintName(name string, kind Kind, ignore ...NameRule) []NameViolation {
+	var violations []NameViolation
+	add := func(rule NameRule, format string, args ...any) {
+		if !slices.Contains(ignore, rule) {
+			violations = append(violations, NameViolation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+		}
+	}
+	if strings.Contains(name, ":") {
+		add(RuleColon, "colons are reserved for recording rules")
+	}
+	for _, part := range strings.Split(name, ":") {
+		if part != "" && !snakeCasePattern.MatchString(part) {
+			add(RuleSnakeCase, "%q is not snake_case", part)
+			break
+		}
+	}
+	if kind == KindCounter && !strings.HasSuffix(name, "_total") {
+		add(RuleCounterTotal, "counter should end with _total")
+	}
+	if kind != KindCounter && strings.HasSuffix(name, "_total") {
+		add(RuleReservedSuffix, "_total is reserved for counters")
+	}
+	for _, suffix := range reservedSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			add(RuleReservedSuffix, "%s is reserved", suffix)
+		}
+	}
+	var units []string
+	per := false
+	for _, word := range strings.FieldsFunc(strings.ToLower(name), func(r rune) bool { return r == '_' || r == ':' }) {
+		if word == "per" {
+			per = true
+		}
+		if base, ok := nonBaseUnits[word]; ok {
+			add(RuleBaseUnit, "use base unit %s instead of %s", base, word)
+			word = base
+		}
+		if slices.Contains(baseUnits, word) && !slices.Contains(units, word) {
+			units = append(units, word)
+		}
+	}
+	if len(units) > 1 && !per {
+		add(RuleUnitMixing, "mixed units %v", units)
+	}
+	return violations
+}
+
+// FixName 修正指标名中可以自动修正的问题, 返回修正后的名称:
+// 转换为 snake_case, 冒号替换为下划线, counter 补充 _total 后缀.
+// 单位及保留后缀不会修改(修改单位需要同时换算数值). ignore 指定的规则不会修正.
+//
+//	monitor.FixName("counter:reqCount", monitor.KindCounter) // counter_req_count_total
+//	monitor.FixName("counter:req", monitor.KindCounter, monitor.RuleColon) // counter:req_total
+func FixName(name string, kind Kind, ignore ...NameRule) string {
+	fix := func(rule NameRule) bool { return !slices.Contains(ignore, rule) }
+	parts := strings.Split(name, ":")
+	if fix(RuleSnakeCase) {
+		for i, part := range parts {
+			parts[i] = snakeCase(part)
+		}
+	}
+	fixed := strings.Join(parts, choose.If(fix(RuleColon), "_", ":"))
+	if fix(RuleSnakeCase) {
+		for strings.Contains(fixed, "__") {
+			fixed = strings.ReplaceAll(fixed, "__", "_")
+		}
+		fixed = strings.TrimSuffix(fixed, "_")
+		if fixed != "" && fixed[0] >= '0' && fixed[0] <= '9' {
+			fixed = "_" + fixed
+		}
+	}
+	if fix(RuleCounterTotal) && kind == KindCounter && !strings.HasSuffix(fixed, "_total") {
+		fixed += "_total"
+	}
+	return fixed
+}
+
+// snakeCase 转换为小写字母, 数字及单个下划线
+func snakeCase(s string) string {
+	var sb strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case unicode.IsUpper(r):
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+				sb.WriteByte('_')
+			}
+			sb.WriteRune(unicode.ToLower(r))
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			sb.WriteRune(r)
+		default:
+			sb.WriteByte('_')
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "_")
+}
+
+// nameLinter 打点时检查指标名的配置
+type nameLinter struct {
+	fix     bool
+	ignore  []NameRule
+	handler func(context.Context, NameLint)
+	checked *syncs.Map[string, string] // kind+完整指标名 => 实际使用的指标名
+	owners  *syncs.Map[string, string] // 实际使用的指标名 => 完整指标名, 用于检查修正后的指标名冲突
+}
+
+// LintOpt 指标命名检查的选项
+type LintOpt func(*nameLinter)
+
+// WithNameLint 打点时按 prometheus 的命名规范检查完整指标名
+//
+// 每个指标名只检查一次, 不符合时默认通过 logger 输出(消息为 name_lint),
+// 并记录到内部异常指标中(kind 为 name_lint).
+// 注意默认的指标名使用冒号分隔且 counter 没有 _total 后缀, 开启后通常需要配合 LintIgnore 或 LintFix 使用.
+//
+//	c := monitor.NewClient(monitor.WithNameLint(monitor.LintIgnore(monitor.RuleColon)))
+func WithNameLint(opts ...LintOpt) Opt {
+	return func(c *client) {
+		l := &nameLinter{checked: syncs.NewMap[string, string](), owners: syncs.NewMap[string, string]()}
+		for _, opt := range opts {
+			opt(l)
+		}
+		c.lint = l
+	}
+}
+
+// LintFix 自动修正指标名(参见 FixName), 使用修正后的名称注册指标
+// 开启 WithUTF8Names 时, 合法的 UTF-8 指标名不会转换为 snake_case.
+// 修正后的名称与其他指标名相同(如 reqCount 与 req_count)时, 不修正并通过 logger 输出(消息为 name_lint|FixCollision).
+func LintFix() LintOpt {
+	return func(l *nameLinter) {
+		l.fix = true
+	}
+}
+
+// LintIgnore 忽略指定的规则
+func LintIgnore(rules ...NameRule) LintOpt {
+	return func(l *nameLinter) {
+		l.ignore = append(l.ignore, rules...)
+	}
+}
+
+// LintHandler 自定义检查结果的处理, 替代默认的输出日志
+func LintHandler(handler func(ctx context.Context, lint NameLint)) LintOpt {
+	return func(l *nameLinter) {
+		l.handler = handler
+	}
+}
+
+// lintName 检查打点事件的指标名, 返回实际使用的指标名
+func (c *client) lintName(ctx context.Context, e Event) string {
+	if c.lint == nil {
+		return e.Name
+	}
+	key := string(e.Kind) + ":" + e.Name
+	if name, ok := c.lint.checked.Load(key); ok {
+		return name
+	}
+	lint := NameLint{Name: e.Name, Kind: e.Kind, Fixed: e.Name}
+	lint.Violations = LintName(e.Name, e.Kind, c.lint.ignore...)
+	if c.lint.fix && len(lint.Violations) > 0 {
+		ignore := c.lint.ignore
+		if c.utf8Names && utf8.ValidString(e.Name) {
+			ignore = append(slices.Clip(ignore), RuleSnakeCase)
+		}
+		lint.Fixed = FixName(e.Name, e.Kind, ignore...)
+	}
+	if owner, loaded := c.lint.owners.LoadOrStore(lint.Fixed, e.Name); loaded && owner != e.Name {
+		// 如 counter:总量 与 counter:数量 都会修正为 counter_total
+		c.recordErr(e.Name, "name_lint_collision")
+		c.logger(ctx, "name_lint|FixCollision", "name", e.Name, "fixed", lint.Fixed, "owner", owner)
+		lint.Fixed = e.Name
+	}
+	if name, loaded := c.lint.checked.LoadOrStore(key, lint.Fixed); loaded {
+		return name
+	}
+	if len(lint.Violations) > 0 {
+		handler := choose.If(c.lint.handler != nil, c.lint.handler, c.logNameLint)
+		handler(ctx, lint)
+	}
+	return lint.Fixed
+}
+
+func (c *client) logNameLint(ctx context.Context, lint NameLint) {
+	c.recordErr(lint.Name, "name_lint")
+	c.logger(ctx, "name_lint", "name", lint.Name, "kind", lint.Kind, "violations", lint.Violations, "fixed", lint.Fixed)
+}
@@ -0,0 +1,105 @@
+package monitor_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"code.gopub.tech/commons/assert"
+	"code.gopub.tech/monitor"
+)
+
+func TestLintName(t *testing.T) {
+	rules := func(name string, kind monitor.Kind, ignore ...monitor.NameRule) (rules []monitor.NameRule) {
+		for _, v := range monitor.LintName(name, kind, ignore...) {
+			rules = append(rules, v.Rule)
+		}
+		return
+	}
+	assert.True(t, rules("http_requests_total", monitor.KindCounter) == nil)
+	assert.True(t, rules("http_request_duration_seconds", monitor.KindHistogram) == nil)
+	assert.True(t, rules("network_bytes_per_second", monitor.KindGauge) == nil)
+	assert.DeepEqual(t, rules("counter:req", monitor.KindCounter), []monitor.NameRule{monitor.RuleColon, monitor.RuleCounterTotal})
+	assert.DeepEqual(t, rules("counter:req", monitor.KindCounter, monitor.RuleColon), []monitor.NameRule{monitor.RuleCounterTotal})
+	assert.DeepEqual(t, rules("apiLatency_ms", monitor.KindHistogram), []monitor.NameRule{monitor.RuleSnakeCase, monitor.RuleBaseUnit})
+	assert.DeepEqual(t, rules("io_seconds_bytes", monitor.KindGauge), []monitor.NameRule{monitor.RuleUnitMixing})
+	assert.DeepEqual(t, rules("queue_count", monitor.KindGauge), []monitor.NameRule{monitor.RuleReservedSuffix})
+	assert.DeepEqual(t, rules("queue_total", monitor.KindGauge), []monitor.NameRule{monitor.RuleReservedSuffix})
+
+	assert.True(t, monitor.FixName("counter:req", monitor.KindCounter) == "counter_req_total")
+	assert.True(t, monitor.FixName("db:apiLatency__seconds", monitor.KindHistogram) == "db_api_latency_seconds")
+	assert.True(t, monitor.FixName("jobs_total", monitor.KindCounter) == "jobs_total")
+	// 忽略的规则不会修正
+	assert.True(t, monitor.FixName("counter:req", monitor.KindCounter, monitor.RuleColon) == "counter:req_total")
+	assert.True(t, monitor.FixName("db:apiLatency", monitor.KindCounter, monitor.RuleColon, monitor.RuleCounterTotal) == "db:api_latency")
+	assert.True(t, monitor.FixName("db:apiLatency", monitor.KindGauge, monitor.RuleSnakeCase) == "db_apiLatency")
+}
+
+func TestWithNameLint(t *testing.T) {
+	var lints []monitor.NameLint
+	c := monitor.NewClient(monitor.WithNameLint(
+		monitor.LintFix(),
+		monitor.LintHandler(func(_ context.Context, lint monitor.NameLint) { lints = append(lints, lint) }),
+	))
+	c.Record(ctx, "req", "请求数")
+	c.Record(ctx, "req", "请求数") // 每个指标名只检查一次
+	c.Sub("db").Store(ctx, "conns", "连接数", 3)
+
+	assert.True(t, len(lints) == 2, lints)
+	assert.True(t, lints[0].Name == "counter:req" && lints[0].Fixed == "counter_req_total", lints[0])
+	assert.True(t, lints[1].Name == "db:gauge:conns" && lints[1].Fixed == "db_gauge_conns", lints[1])
+
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	t.Log(body)
+	assert.True(t, strings.Contains(body, "\ncounter_req_total 2\n"))
+	assert.True(t, strings.Contains(body, "\ndb_gauge_conns 3\n"))
+	assert.True(t, strings.Contains(c.Catalog()[0].Name, "counter_req_total"))
+
+	var logs []string
+	c = monitor.NewClient(
+		monitor.WithNameLint(monitor.LintIgnore(monitor.RuleColon)),
+		monitor.WithLogger(func(_ context.Context, msg string, _ ...any) { logs = append(logs, msg) }),
+	)
+	c.Record(ctx, "req", "请求数")
+	assert.DeepEqual(t, logs, []string{"name_lint"})
+	w = httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.True(t, strings.Contains(w.Body.String(), `internal_monitor_error{kind="name_lint",name="counter:req"} 1`))
+	assert.True(t, strings.Contains(w.Body.String(), "\ncounter:req 1\n"))
+}
+
+func TestLintFixIgnore(t *testing.T) {
+	c := monitor.NewClient(monitor.WithNameLint(
+		monitor.LintFix(),
+		monitor.LintIgnore(monitor.RuleColon),
+		monitor.LintHandler(func(context.Context, monitor.NameLint) {}),
+	))
+	c.Record(ctx, "req", "请求数")
+	c.Store(ctx, "g", "瞬时值", 1)
+
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	assert.True(t, strings.Contains(body, "\ncounter:req_total 1\n"), body)
+	assert.True(t, strings.Contains(body, "\ngauge:g 1\n"), body)
+}
+
+func TestLintFixCollision(t *testing.T) {
+	var logs []string
+	c := monitor.NewClient(
+		monitor.WithNameLint(monitor.LintFix(), monitor.LintHandler(func(context.Context, monitor.NameLint) {})),
+		monitor.WithLogger(func(_ context.Context, msg string, _ ...any) { logs = append(logs, msg) }),
+	)
+	c.Record(ctx, "req_count", "请求数")
+	c.Record(ctx, "reqCount", "请求数")
+	assert.DeepEqual(t, logs, []string{"name_lint|FixCollision"})
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	t.Log(body)
+	assert.True(t, strings.Contains(body, "\ncounter_req_count_total 1\n"))
+	assert.True(t, strings.Contains(body, `internal_monitor_error{kind="name_lint_collision",name="counter:reqCount"} 1`))
+}
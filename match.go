@@ -0,0 +1,69 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// REGEXP_PREFIX 以此开头的指标名模式是正则表达式, 参见 WithMetricBuckets
+const REGEXP_PREFIX = "re:"
+
+// metricMatcher 判断打点事件是否匹配指标名模式
+type metricMatcher func(e Event) bool
+
+// compileMatcher 编译指标名模式, 调用时传入的指标名或完整指标名匹配即可
+//
+//	db_query          // 完全相同
+//	db_*              // glob, 包含 * ? [ 时使用 path.Match 匹配
+//	re:^(db|cache)_   // 正则表达式, 部分匹配即可, 需要完整匹配时请使用 ^$
+func compileMatcher(pattern string) (metricMatcher, error) {
+	if expr, ok := strings.CutPrefix(pattern, REGEXP_PREFIX); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metric pattern %q: %w", pattern, err)
+		}
+		return func(e Event) bool {
+			return re.MatchString(e.Metric) || re.MatchString(e.Name)
+		}, nil
+	}
+	if strings.ContainsAny(pattern, "*?[") {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid metric pattern %q: %w", pattern, err)
+		}
+		return func(e Event) bool {
+			ok1, _ := path.Match(pattern, e.Metric)
+			ok2, _ := path.Match(pattern, e.Name)
+			return ok1 || ok2
+		}, nil
+	}
+	return func(e Event) bool {
+		return pattern == e.Metric || pattern == e.Name
+	}, nil
+}
+
+// compileMatchers 编译多个指标名模式, 跳过无效的模式并返回所有错误
+func compileMatchers(patterns []string) ([]metricMatcher, error) {
+	matchers := make([]metricMatcher, 0, len(patterns))
+	var errs []error
+	for _, pattern := range patterns {
+		m, err := compileMatcher(pattern)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers, errors.Join(errs...)
+}
+
+func matchAny(matchers []metricMatcher, e Event) bool {
+	for _, m := range matchers {
+		if m(e) {
+			return true
+		}
+	}
+	return false
+}
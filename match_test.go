@@ -0,0 +1,73 @@
+package monitor_test
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"code.gopub.tech/commons/assert"
+	"code.gopub.tech/monitor"
+)
+
+func TestMetricPatterns(t *testing.T) {
+	c := monitor.NewClient(
+		monitor.WithNamespace("app"),
+		monitor.WithMetricBuckets("db_*", .001, .01),
+		monitor.WithMetricBuckets("re:^batch_", 60, 600),
+		monitor.WithMetricBuckets("db_slow_*", 1, 10), // 后添加的优先
+		monitor.WithMetricObjectives("app:summary:rpc_?", map[float64]float64{0.9: 0.01}),
+		monitor.WithDisabledMetrics("re:_debug$"),
+	)
+	c.Cost(ctx, "db_query", "查询耗时", time.Millisecond*5)
+	c.Cost(ctx, "db_slow_scan", "慢查询耗时", time.Second*5)
+	c.Cost(ctx, "batch_export", "导出耗时", time.Minute*2)
+	c.Cost(ctx, "api", "接口耗时", time.Millisecond*5)
+	c.CostBuckets(ctx, "db_fixed", "指定分布", time.Millisecond*5, []time.Duration{time.Second})
+	c.Summary(ctx, "rpc_a", "调用耗时", 1)
+	c.Summary(ctx, "rpc_ab", "调用耗时", 1)
+	c.Record(ctx, "cache_debug", "调试计数")
+
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	t.Log(body)
+	for _, want := range []string{
+		`app:timer:db_query_seconds_bucket{le="0.001"} 0`,
+		`app:timer:db_query_seconds_bucket{le="0.01"} 1`,
+		`app:timer:db_slow_scan_seconds_bucket{le="10"} 1`,
+		`app:timer:batch_export_seconds_bucket{le="600"} 1`,
+		`app:timer:api_seconds_bucket{le="0.005"} 1`,  // 默认分布
+		`app:timer:db_fixed_seconds_bucket{le="1"} 1`, // 调用时指定的分布优先
+		`app:summary:rpc_a{quantile="0.9"} 1`,
+	} {
+		assert.True(t, strings.Contains(body, want), want)
+	}
+	assert.True(t, !strings.Contains(body, `db_slow_scan_seconds_bucket{le="0.001"}`))
+	assert.True(t, !strings.Contains(body, `rpc_ab{quantile`))
+	assert.True(t, !strings.Contains(body, "cache_debug"))
+}
+
+func TestInvalidMetricPattern(t *testing.T) {
+	var logs []string
+	c := monitor.NewClient(
+		monitor.WithLogger(func(_ context.Context, msg string, args ...any) {
+			logs = append(logs, msg+fmt.Sprint(args...))
+		}),
+		monitor.WithMetricBuckets("re:(", 1),
+		monitor.WithDisabledMetrics("[", "api"),
+	)
+	assert.True(t, len(logs) == 1 && strings.Contains(logs[0], `"re:("`) && strings.Contains(logs[0], `"["`), logs)
+	// 有效的模式仍然生效
+	c.Record(ctx, "api", "接口调用")
+	families, err := c.Registry().Gather()
+	assert.True(t, err == nil && len(families) == 0, families)
+
+	_, err = c.Reconfigure(monitor.WithEnabledMetrics("re:[a-"))
+	assert.True(t, err != nil && strings.Contains(err.Error(), "re:[a-"), err)
+
+	_, err = monitor.LoadConfig(strings.NewReader("metrics: [{name: 're:(', buckets: [1]}]"))
+	assert.True(t, err != nil && strings.Contains(err.Error(), "metrics[0].name"), err)
+}
@@ -0,0 +1,182 @@
+package monitor
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// HTTPOpt HTTP 打点的配置项
+type HTTPOpt func(*httpConfig)
+
+type httpConfig struct {
+	name        string
+	buckets     []float64
+	sizeBuckets []float64
+	route       func(*http.Request) string
+	scopeLabels []string
+}
+
+// HTTPName 设置指标名前缀
+// HTTPMiddleware 默认值是 "http_server", RoundTripper 默认值是 "http_client"
+func HTTPName(name string) HTTPOpt {
+	return func(hc *httpConfig) {
+		hc.name = name
+	}
+}
+
+// HTTPBuckets 设置耗时分布
+// 默认值是 client 的默认分布, 参见 WithBuckets
+func HTTPBuckets(buckets ...float64) HTTPOpt {
+	return func(hc *httpConfig) {
+		hc.buckets = buckets
+	}
+}
+
+// HTTPSizeBuckets 设置请求/响应大小分布(单位: 字节)
+// 默认值是 100B, 1KB, 10KB, 100KB, 1MB, 10MB, 100MB
+func HTTPSizeBuckets(buckets ...float64) HTTPOpt {
+	return func(hc *httpConfig) {
+		hc.sizeBuckets = buckets
+	}
+}
+
+// HTTPRoute 设置 route 标签的取值方式, 仅用于 HTTPMiddleware
+// 默认值是 Go 1.22+ 的 [http.Request.Pattern] (未匹配到路由时为空字符串),
+// 使用路由模式而不是 URL 作为标签, 避免标签值过多.
+func HTTPRoute(route func(*http.Request) string) HTTPOpt {
+	return func(hc *httpConfig) {
+		hc.route = route
+	}
+}
+
+// HTTPScopeLabels 设置 handler 中通过 CtxAddLabels 添加后, 需要附加到请求指标上的标签名, 仅用于 HTTPMiddleware
+// 默认值为空, 表示不附加. 只附加指定的标签, handler 未添加的标签值为空字符串, 使所有请求的标签名保持一致.
+//
+//	h := c.HTTPMiddleware(mux, monitor.HTTPScopeLabels("tenant"))
+func HTTPScopeLabels(names ...string) HTTPOpt {
+	return func(hc *httpConfig) {
+		hc.scopeLabels = names
+	}
+}
+
+func (c *client) newHTTPConfig(name string, opts ...HTTPOpt) *httpConfig {
+	hc := &httpConfig{
+		name:        name,
+		sizeBuckets: prometheus.ExponentialBuckets(100, 10, 7),
+		route:       func(r *http.Request) string { return r.Pattern },
+	}
+	for _, opt := range opts {
+		opt(hc)
+	}
+	return hc
+}
+
+// HTTPMiddleware 返回记录 HTTP 请求指标的中间件
+//
+// 标签为 method, code, route 以及 ctx 上的标签.
+// handler 中通过 CtxAddLabels 往请求的 ctx 上添加的标签, 只有 HTTPScopeLabels 指定的会附加到指标上
+// (同一个指标的标签名需要保持一致).
+// route 标签需要在 http.ServeMux 完成路由后才能取到, 因此中间件应包在 ServeMux 外层.
+//
+//	// namespace:subsystem:counter:http_server_requests
+//	// namespace:subsystem:timer:http_server_request_duration_seconds
+//	// namespace:subsystem:histogram:http_server_request_size_bytes
+//	// namespace:subsystem:histogram:http_server_response_size_bytes
+//	// namespace:subsystem:gauge:http_server_in_flight_requests (只有 method 标签)
+//	http.ListenAndServe(":8080", c.HTTPMiddleware(mux))
+func (c *client) HTTPMiddleware(next http.Handler, opts ...HTTPOpt) http.Handler {
+	hc := c.newHTTPConfig("http_server", opts...)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx := r.Context()
+		c.Add(ctx, hc.name+"_in_flight_requests", "处理中的 HTTP 请求数", 1, "method", r.Method)
+		defer c.Add(ctx, hc.name+"_in_flight_requests", "处理中的 HTTP 请求数", -1, "method", r.Method)
+
+		scopeCtx, scope := ctxWithLabelScope(ctx)
+		r = r.WithContext(scopeCtx)
+		body := &countingReader{ReadCloser: r.Body}
+		if r.Body != nil {
+			r.Body = body
+		}
+		rw := &responseWriter{ResponseWriter: w, code: http.StatusOK}
+		next.ServeHTTP(rw, r)
+
+		cost := time.Since(start)
+		kvs := append(scope.kvs(hc.scopeLabels), "method", r.Method, "code", strconv.Itoa(rw.code), "route", hc.route(r))
+		c.Record(ctx, hc.name+"_requests", "HTTP 请求数", kvs...)
+		c.recordHistogram(ctx, hc.name+"_request_duration", "HTTP 请求耗时", timerNames,
+			c.timeValue(cost), hc.buckets, kvs...)
+		c.Histogram(ctx, hc.name+"_request_size_bytes", "HTTP 请求体大小", body.n, hc.sizeBuckets, kvs...)
+		c.Histogram(ctx, hc.name+"_response_size_bytes", "HTTP 响应体大小", rw.n, hc.sizeBuckets, kvs...)
+	})
+}
+
+// responseWriter 记录响应状态码和响应体大小
+type responseWriter struct {
+	http.ResponseWriter
+	code        int
+	n           int64
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.code = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.n += int64(n)
+	return n, err
+}
+
+// Flush 实现 http.Flusher
+func (w *responseWriter) Flush() {
+	w.wroteHeader = true
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack 实现 http.Hijacker, 用于 websocket 等协议升级
+// 未写入响应头时状态码记录为 101
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil && !w.wroteHeader {
+		w.code, w.wroteHeader = http.StatusSwitchingProtocols, true
+	}
+	return conn, rw, err
+}
+
+// Push 实现 http.Pusher
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := w.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap 供 http.ResponseController 获取原始的 ResponseWriter
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// countingReader 记录读取的字节数
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,85 @@
+package monitor_test
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"code.gopub.tech/commons/assert"
+	"code.gopub.tech/monitor"
+)
+
+func TestHTTPMiddleware(t *testing.T) {
+	c := monitor.NewClient()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		monitor.CtxAddLabels(r.Context(), "tenant", "t1")
+		w.Write([]byte("hello"))
+	})
+	mux.HandleFunc("POST /users", func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		monitor.CtxAddLabels(r.Context(), "tenant", "t2")
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		monitor.CtxAddLabels(r.Context(), "debug", "1") // 未指定的标签不会附加
+	})
+	h := c.HTTPMiddleware(mux, monitor.HTTPScopeLabels("tenant"))
+
+	for _, id := range []string{"1", "2", "3"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/"+id, nil))
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/users", strings.NewReader("name=abc")))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	t.Log(body)
+	assert.True(t, strings.Contains(body, `counter:http_server_requests{code="200",method="GET",route="GET /users/{id}",tenant="t1"} 3`))
+	assert.True(t, strings.Contains(body, `counter:http_server_requests{code="201",method="POST",route="POST /users",tenant="t2"} 1`))
+	assert.True(t, strings.Contains(body, `timer:http_server_request_duration_seconds_count{code="200",method="GET",route="GET /users/{id}",tenant="t1"} 3`))
+	assert.True(t, strings.Contains(body, `histogram:http_server_request_size_bytes_sum{code="201",method="POST",route="POST /users",tenant="t2"} 8`))
+	assert.True(t, strings.Contains(body, `histogram:http_server_response_size_bytes_sum{code="200",method="GET",route="GET /users/{id}",tenant="t1"} 15`))
+	assert.True(t, strings.Contains(body, `gauge:http_server_in_flight_requests{method="GET"} 0`))
+	assert.True(t, strings.Contains(body, `counter:http_server_requests{code="200",method="GET",route="GET /health",tenant=""} 1`))
+	assert.True(t, !strings.Contains(body, "debug"))
+}
+
+func TestHTTPMiddlewareHijack(t *testing.T) {
+	c := monitor.NewClient()
+	h := c.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, ok := w.(http.Pusher)
+		assert.True(t, ok)
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		assert.True(t, err == nil, err)
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: test\r\nConnection: Upgrade\r\n\r\nhello")
+		rw.Flush()
+	}))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	assert.True(t, err == nil, err)
+	defer conn.Close()
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: test\r\nUpgrade: test\r\nConnection: Upgrade\r\n\r\n"))
+	data, _ := io.ReadAll(conn)
+	assert.True(t, strings.HasSuffix(string(data), "\r\n\r\nhello"), string(data))
+
+	// 连接关闭后 handler 才返回并记录指标
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		w := httptest.NewRecorder()
+		c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		if strings.Contains(w.Body.String(), `counter:http_server_requests{code="101",method="GET",route=""} 1`) {
+			break
+		}
+		assert.True(t, time.Now().Before(deadline), w.Body.String())
+		time.Sleep(time.Millisecond * 10)
+	}
+}
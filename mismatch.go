@@ -0,0 +1,168 @@
+package monitor
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"math"
+	"slices"
+	"time"
+
+	"code.gopub.tech/commons/syncs"
+	"code.gopub.tech/commons/values"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MismatchStrategy 打点时的分布/分位数与已注册指标不一致时的处理方式
+type MismatchStrategy string
+
+const (
+	// MismatchKeepFirst 仍然记录到已注册的指标中(使用最先注册时的分布/分位数), 是默认值
+	MismatchKeepFirst MismatchStrategy = "keep_first"
+	// MismatchSibling 记录到名称追加了分布/分位数哈希后缀的另一个指标中, 如 timer:api_seconds_1a2b3c4d
+	MismatchSibling MismatchStrategy = "sibling"
+	// MismatchReplace 注销已注册的指标(已记录的数据会丢失), 按新的分布/分位数重新创建
+	// 同一指标在 replaceInterval 内最多替换一次, 期间不一致的打点仍记录到当前的指标中,
+	// 避免多个调用点交替使用不同的分布时指标被反复重建. 仅适用于迁移分布等场景.
+	// 使用该方式时, 每次写入 histogram/summary 都会持有读锁, 替换时持有写锁, 不会写入已注销的指标.
+	MismatchReplace MismatchStrategy = "replace"
+	// MismatchReject 丢弃本次打点
+	MismatchReject MismatchStrategy = "reject"
+)
+
+// replaceInterval MismatchReplace 时同一指标两次替换的最小间隔
+const replaceInterval = time.Minute
+
+// WithMismatchStrategy 设置打点时的分布/分位数与已注册指标不一致时的处理方式
+// 不论使用哪种方式, 都会通过 logger 输出, 并记录到内部异常指标中(kind 为 histogram_buckets_mismatch 或 summary_objectives_mismatch).
+// 默认值是 MismatchKeepFirst
+func WithMismatchStrategy(strategy MismatchStrategy) Opt {
+	return func(c *client) {
+		c.mismatch = strategy
+	}
+}
+
+// histogramFor 返回 histogram 事件应当写入的指标及其名称, 返回 false 表示丢弃本次打点
+// MismatchReplace 时调用方需持有 replaceMu 的读锁
+func (c *client) histogramFor(ctx context.Context, e Event) (*prometheus.HistogramVec, string, bool) {
+	opt := prometheus.HistogramOpts{
+		Name:        e.Name,
+		Help:        e.Help,
+		ConstLabels: e.ConstLabels,
+		Buckets:     e.Buckets,
+	}
+	v := c.getHistogram(ctx, opt, e.labelNames())
+	if slices.Equal(v.Val2.Buckets, e.Buckets) {
+		return v.Val1, e.Name, true
+	}
+	c.recordErr(e.Name, "histogram_buckets_mismatch")
+	c.logger(ctx, "histogram_buckets_mismatch",
+		"name", e.Name, "help", e.Help,
+		"wantBucket", e.Buckets, "actual", v.Val2.Buckets, "strategy", c.mismatch,
+	)
+	switch c.mismatch {
+	case MismatchSibling:
+		opt.Name = siblingName(e.Name, e.Buckets...)
+		v = c.getHistogram(ctx, opt, e.labelNames())
+	case MismatchReplace:
+		var ok bool
+		v, ok = replaceVec(ctx, c, c.histogram, e.Name,
+			func(opt prometheus.HistogramOpts) bool { return slices.Equal(opt.Buckets, e.Buckets) },
+			func() values.Tuple2[*prometheus.HistogramVec, prometheus.HistogramOpts] {
+				return c.getHistogram(ctx, opt, e.labelNames())
+			})
+		if !ok {
+			return nil, "", false
+		}
+	case MismatchReject:
+		return nil, "", false
+	}
+	return v.Val1, opt.Name, true
+}
+
+// summaryFor 返回 summary 事件应当写入的指标及其名称, 返回 false 表示丢弃本次打点
+// MismatchReplace 时调用方需持有 replaceMu 的读锁
+func (c *client) summaryFor(ctx context.Context, e Event) (*prometheus.SummaryVec, string, bool) {
+	opt := prometheus.SummaryOpts{
+		Name:        e.Name,
+		Help:        e.Help,
+		ConstLabels: e.ConstLabels,
+		Objectives:  e.Objectives,
+	}
+	v := c.getSummary(ctx, opt, e.labelNames())
+	if maps.Equal(v.Val2.Objectives, e.Objectives) {
+		return v.Val1, e.Name, true
+	}
+	c.recordErr(e.Name, "summary_objectives_mismatch")
+	c.logger(ctx, "summary_objectives_mismatch",
+		"name", e.Name, "help", e.Help,
+		"wantObjectives", e.Objectives, "actual", v.Val2.Objectives, "strategy", c.mismatch,
+	)
+	switch c.mismatch {
+	case MismatchSibling:
+		var flat []float64
+		for _, q := range slices.Sorted(maps.Keys(e.Objectives)) {
+			flat = append(flat, q, e.Objectives[q])
+		}
+		opt.Name = siblingName(e.Name, flat...)
+		v = c.getSummary(ctx, opt, e.labelNames())
+	case MismatchReplace:
+		var ok bool
+		v, ok = replaceVec(ctx, c, c.summary, e.Name,
+			func(opt prometheus.SummaryOpts) bool { return maps.Equal(opt.Objectives, e.Objectives) },
+			func() values.Tuple2[*prometheus.SummaryVec, prometheus.SummaryOpts] {
+				return c.getSummary(ctx, opt, e.labelNames())
+			})
+		if !ok {
+			return nil, "", false
+		}
+	case MismatchReject:
+		return nil, "", false
+	}
+	return v.Val1, opt.Name, true
+}
+
+// replaceVec 注销布局(分布/分位数)不一致的已注册指标, 并重新创建
+// 调用方持有 replaceMu 的读锁; 替换期间释放读锁并持有写锁, 返回前重新持有读锁.
+// 重新持有读锁前指标可能又被其他调用替换, 此时返回 false, 丢弃本次打点.
+func replaceVec[V interface {
+	comparable
+	prometheus.Collector
+}, O any](
+	ctx context.Context, c *client, cache *syncs.Map[string, values.Tuple2[V, O]], name string,
+	same func(O) bool, create func() values.Tuple2[V, O],
+) (values.Tuple2[V, O], bool) {
+	c.replaceMu.RUnlock()
+	c.replaceMu.Lock()
+	cur, ok := cache.Load(name)
+	if ok && !same(cur.Val2) {
+		if last, replaced := c.replaced[name]; replaced && time.Since(last) < replaceInterval {
+			// 刚替换过, 不再替换, 避免交替使用不同布局时反复重建
+			c.logger(ctx, "mismatch_replace|Throttled", "name", name, "lastReplace", last)
+		} else {
+			c.registry.Unregister(cur.Val1)
+			cache.Delete(name)
+			c.replaced[name] = time.Now()
+		}
+	}
+	v := create()
+	c.replaceMu.Unlock()
+	c.replaceMu.RLock()
+	if cur, ok := cache.Load(name); !ok || cur.Val1 != v.Val1 {
+		c.recordErr(name, "mismatch_replace_conflict")
+		c.logger(ctx, "mismatch_replace|Conflict", "name", name)
+		return v, false
+	}
+	return v, true
+}
+
+// siblingName 在指标名后追加分布/分位数的哈希
+func siblingName(name string, layout ...float64) string {
+	h := fnv.New32a()
+	for _, f := range layout {
+		h.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+	}
+	return fmt.Sprintf("%s_%08x", name, h.Sum32())
+}
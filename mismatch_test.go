@@ -0,0 +1,86 @@
+package monitor_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"code.gopub.tech/commons/assert"
+	"code.gopub.tech/monitor"
+)
+
+func TestMismatchStrategy(t *testing.T) {
+	record := func(strategy monitor.MismatchStrategy) string {
+		c := monitor.NewClient(monitor.WithMismatchStrategy(strategy))
+		c.Histogram(ctx, "size", "大小", 5, []float64{1, 10})
+		c.Histogram(ctx, "size", "大小", 5, []float64{2, 20})
+		c.SummaryObjectives(ctx, "avg", "平均", 5, map[float64]float64{0.5: 0.05})
+		c.SummaryObjectives(ctx, "avg", "平均", 5, map[float64]float64{0.9: 0.01})
+		w := httptest.NewRecorder()
+		c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		body := w.Body.String()
+		t.Log(body)
+		assert.True(t, strings.Contains(body, `kind="histogram_buckets_mismatch",name="histogram:size"} 1`))
+		assert.True(t, strings.Contains(body, `kind="summary_objectives_mismatch",name="summary:avg"} 1`))
+		return body
+	}
+
+	body := record(monitor.MismatchKeepFirst)
+	assert.True(t, strings.Contains(body, `histogram:size_bucket{le="10"} 2`))
+	assert.True(t, strings.Contains(body, `summary:avg_count 2`))
+
+	body = record(monitor.MismatchSibling)
+	assert.True(t, strings.Contains(body, `histogram:size_bucket{le="10"} 1`))
+	assert.True(t, regexp.MustCompile(`histogram:size_[0-9a-f]{8}_bucket\{le="20"\} 1`).MatchString(body))
+	assert.True(t, strings.Contains(body, `summary:avg{quantile="0.5"} 5`))
+	assert.True(t, regexp.MustCompile(`summary:avg_[0-9a-f]{8}\{quantile="0.9"\} 5`).MatchString(body))
+
+	body = record(monitor.MismatchReplace)
+	assert.True(t, !strings.Contains(body, `histogram:size_bucket{le="10"}`))
+	assert.True(t, strings.Contains(body, `histogram:size_bucket{le="20"} 1`))
+	assert.True(t, strings.Contains(body, `summary:avg{quantile="0.9"} 5`))
+	assert.True(t, strings.Contains(body, `summary:avg_count 1`))
+
+	body = record(monitor.MismatchReject)
+	assert.True(t, strings.Contains(body, `histogram:size_bucket{le="10"} 1`))
+	assert.True(t, strings.Contains(body, `histogram:size_count 1`))
+	assert.True(t, strings.Contains(body, `summary:avg_count 1`))
+}
+
+func TestMismatchReplaceAlternate(t *testing.T) {
+	var logs []string
+	var mu sync.Mutex
+	c := monitor.NewClient(monitor.WithMismatchStrategy(monitor.MismatchReplace),
+		monitor.WithLogger(func(_ context.Context, msg string, _ ...any) {
+			mu.Lock()
+			defer mu.Unlock()
+			logs = append(logs, msg)
+		}))
+	c.Histogram(ctx, "size", "大小", 5, []float64{1, 10})
+	c.Histogram(ctx, "size", "大小", 5, []float64{2, 20})
+	// 刚替换过, 交替使用的分布不再替换, 记录到当前的指标中
+	c.Histogram(ctx, "size", "大小", 5, []float64{1, 10})
+	assert.True(t, slices.Contains(logs, "mismatch_replace|Throttled"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.Histogram(ctx, "size", "大小", 5, []float64{float64(i % 2), 20})
+			}
+		}()
+	}
+	wg.Wait()
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	t.Log(body)
+	assert.True(t, strings.Contains(body, `histogram:size_bucket{le="20"} 802`))
+	assert.True(t, !strings.Contains(body, `histogram:size_bucket{le="10"}`))
+}
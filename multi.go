@@ -0,0 +1,126 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Source 一个指标来源
+type Source struct {
+	Name     string              // 来源名称, 用作区分来源的标签值
+	Gatherer prometheus.Gatherer // 通常是 client 的 registry
+
+	logger func(context.Context, string, ...any) // 由 client.Source 设置, 用于输出合并时的错误
+}
+
+// Source 返回以 client 的 registry 为指标来源的 Source
+func (c *client) Source(name string) Source {
+	return Source{Name: name, Gatherer: c.registry, logger: c.logger}
+}
+
+// MultiHandler 将多个 client 的指标合并到一个 http.Handler 中暴露
+// 使用同一个 registry 的 client (如 Sub 派生的子 client) 只会暴露一次.
+// 指标冲突(同名指标类型/说明不同, 或时间序列重复)时, 冲突的指标会被跳过,
+// 并通过第一个 client 的 logger (参见 WithLogger) 输出.
+//
+//	http.Handle("/metrics", monitor.MultiHandler(c1, c2))
+func MultiHandler(clients ...*client) http.Handler {
+	sources := make([]Source, 0, len(clients))
+	for _, c := range clients {
+		sources = append(sources, c.Source(""))
+	}
+	return MultiHandlerFor("", sources...)
+}
+
+// MultiHandlerFor 将多个来源的指标合并到一个 http.Handler 中暴露
+// labelName 不为空时, 每个来源的指标都会添加 labelName=Source.Name 标签, 用于区分来源.
+// 合并时的错误通过第一个由 client.Source 创建的来源的 logger 输出, 没有时使用 [slog.WarnContext].
+//
+//	http.Handle("/metrics", monitor.MultiHandlerFor("component",
+//		c1.Source("api"), c2.Source("worker"),
+//	))
+func MultiHandlerFor(labelName string, sources ...Source) http.Handler {
+	return promhttp.HandlerFor(MultiGatherer(labelName, sources...), promhttp.HandlerOpts{
+		ErrorLog:      errorLogger{logger: sourceLogger(sources)},
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
+
+// MultiGatherer 合并多个来源的指标, 参见 MultiHandlerFor
+func MultiGatherer(labelName string, sources ...Source) prometheus.Gatherer {
+	var gatherers prometheus.Gatherers
+	seen := map[*prometheus.Registry]bool{}
+	for _, s := range sources {
+		if labelName != "" {
+			gatherers = append(gatherers, &labeledGatherer{
+				Gatherer: s.Gatherer,
+				label:    &dto.LabelPair{Name: &labelName, Value: &s.Name},
+			})
+			continue
+		}
+		if r, ok := s.Gatherer.(*prometheus.Registry); ok {
+			if seen[r] {
+				continue
+			}
+			seen[r] = true
+		}
+		gatherers = append(gatherers, s.Gatherer)
+	}
+	return gatherers
+}
+
+// labeledGatherer 为所有指标添加一个标签, 指标已有同名标签时返回错误
+type labeledGatherer struct {
+	prometheus.Gatherer
+	label *dto.LabelPair
+}
+
+func (g *labeledGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.Gatherer.Gather()
+	var errs prometheus.MultiError
+	if err != nil {
+		errs.Append(err)
+	}
+	for _, mf := range families {
+		metrics := mf.Metric[:0]
+		for _, m := range mf.GetMetric() {
+			if slices.ContainsFunc(m.GetLabel(), func(l *dto.LabelPair) bool { return l.GetName() == g.label.GetName() }) {
+				errs.Append(fmt.Errorf("metric %s already has label %q, source %q skipped",
+					mf.GetName(), g.label.GetName(), g.label.GetValue()))
+				continue
+			}
+			m.Label = append(m.Label, g.label)
+			slices.SortFunc(m.Label, func(a, b *dto.LabelPair) int { return strings.Compare(a.GetName(), b.GetName()) })
+			metrics = append(metrics, m)
+		}
+		mf.Metric = metrics
+	}
+	return families, errs.MaybeUnwrap()
+}
+
+// sourceLogger 返回第一个设置了 logger 的来源的 logger
+func sourceLogger(sources []Source) func(context.Context, string, ...any) {
+	for _, s := range sources {
+		if s.logger != nil {
+			return s.logger
+		}
+	}
+	return slog.WarnContext
+}
+
+// errorLogger 将 promhttp 的错误输出到 logger
+type errorLogger struct {
+	logger func(context.Context, string, ...any)
+}
+
+func (l errorLogger) Println(v ...any) {
+	l.logger(context.Background(), "multi_handler|GatherFailed", "err", fmt.Sprint(v...))
+}
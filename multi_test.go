@@ -0,0 +1,57 @@
+package monitor_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"code.gopub.tech/commons/assert"
+	"code.gopub.tech/monitor"
+)
+
+func TestMultiHandler(t *testing.T) {
+	api := monitor.NewClient(monitor.WithNamespace("api"))
+	worker := monitor.NewClient(monitor.WithNamespace("worker"))
+	db := api.Sub("db")
+	api.Record(ctx, "xxx_throughput", "xxx总量")
+	db.Record(ctx, "xxx_throughput", "xxx总量")
+	worker.Record(ctx, "xxx_throughput", "xxx总量")
+
+	w := httptest.NewRecorder()
+	monitor.MultiHandler(api, worker, db).ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	t.Log(body)
+	assert.True(t, strings.Contains(body, "api:counter:xxx_throughput 1"))
+	assert.True(t, strings.Contains(body, "api:db:counter:xxx_throughput 1"))
+	assert.True(t, strings.Contains(body, "worker:counter:xxx_throughput 1"))
+}
+
+func TestMultiHandlerFor(t *testing.T) {
+	var logs []string
+	c1 := monitor.NewClient(monitor.WithLogger(func(_ context.Context, msg string, _ ...any) { logs = append(logs, msg) }))
+	c2 := monitor.NewClient()
+	c1.Record(ctx, "xxx_throughput", "xxx总量")
+	c2.RecordN(ctx, "xxx_throughput", "xxx总量", 2)
+	c2.Store(ctx, "xxx_current_value", "xxx当前值", 1, "source", "x")
+
+	// 不区分来源时, 同名指标冲突
+	w := httptest.NewRecorder()
+	monitor.MultiHandler(c1, c2).ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	t.Log(w.Body.String())
+	assert.True(t, !strings.Contains(w.Body.String(), "counter:xxx_throughput 2"))
+	// 冲突通过第一个 client 的 logger 输出
+	assert.DeepEqual(t, logs, []string{"multi_handler|GatherFailed"})
+	logs = nil
+
+	w = httptest.NewRecorder()
+	monitor.MultiHandlerFor("source", c1.Source("c1"), c2.Source("c2")).
+		ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	t.Log(body)
+	assert.True(t, strings.Contains(body, `counter:xxx_throughput{source="c1"} 1`))
+	assert.True(t, strings.Contains(body, `counter:xxx_throughput{source="c2"} 2`))
+	// 已有同名标签的指标被跳过
+	assert.True(t, !strings.Contains(body, `gauge:xxx_current_value`))
+	assert.DeepEqual(t, logs, []string{"multi_handler|GatherFailed"})
+}
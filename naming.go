@@ -0,0 +1,81 @@
+package monitor
+
+import (
+	"strings"
+
+	"code.gopub.tech/commons/iters"
+	"code.gopub.tech/commons/values"
+)
+
+// NamingScheme 完整指标名的命名方式
+type NamingScheme string
+
+const (
+	// NamingColon 使用冒号连接名称空间, 子系统及指标名, 指标名带有类型前缀, 如 app:api:counter:req.
+	// 默认值, 与旧版本的指标名一致.
+	NamingColon NamingScheme = "colon"
+	// NamingUnderscore 使用 prometheus 推荐的 namespace_subsystem_name 格式, 如 app_api_req_total:
+	// 使用下划线连接, 没有类型前缀, counter 追加 _total 后缀(指标名已经以后缀结尾时不重复追加).
+	NamingUnderscore NamingScheme = "underscore"
+	// NamingDual 迁移模式, 每次打点同时写入 NamingUnderscore 及 NamingColon 两种指标名,
+	// 便于看板及告警规则逐步切换到新的指标名. Track 的 in_flight 等采集时计算的指标只使用新的指标名.
+	// 只有写入 registry 时使用两种指标名, 其他 Sink 每次打点只收到一个使用新指标名的事件.
+	NamingDual NamingScheme = "dual"
+)
+
+// WithNamingScheme 设置完整指标名的命名方式
+// 默认值是 NamingColon. 未通过 WithNameAppend 设置前缀/后缀时, 使用对应命名方式的默认前缀/后缀.
+//
+//	c := monitor.NewClient(monitor.WithNamespace("app"), monitor.WithNamingScheme(monitor.NamingUnderscore))
+//	c.Record(ctx, "req", "请求数") // app_req_total
+//	c.Sub("db").Cost(ctx, "query", "查询耗时", cost) // app_db_query_seconds
+func WithNamingScheme(scheme NamingScheme) Opt {
+	return func(c *client) {
+		c.naming = scheme
+	}
+}
+
+// defaultNameAppends 命名方式对应的默认前缀/后缀
+func defaultNameAppends(scheme NamingScheme) NameAppends {
+	if scheme == NamingUnderscore || scheme == NamingDual {
+		return NameAppends{
+			Counter: NameAppend{Suffix: "_total"},
+			Timer:   NameAppend{Suffix: "_seconds"},
+		}
+	}
+	return NameAppends{
+		Counter:   NameAppend{Prefix: "counter:"},
+		Gauge:     NameAppend{Prefix: "gauge:"},
+		Timer:     NameAppend{Prefix: "timer:", Suffix: "_seconds"},
+		Histogram: NameAppend{Prefix: "histogram:"},
+		Summary:   NameAppend{Prefix: "summary:"},
+	}
+}
+
+// nameKind 选取某类指标的名称前缀/后缀
+type nameKind func(NameAppends) NameAppend
+
+var (
+	counterNames   nameKind = func(n NameAppends) NameAppend { return n.Counter }
+	gaugeNames     nameKind = func(n NameAppends) NameAppend { return n.Gauge }
+	timerNames     nameKind = func(n NameAppends) NameAppend { return n.Timer }
+	histogramNames nameKind = func(n NameAppends) NameAppend { return n.Histogram }
+	summaryNames   nameKind = func(n NameAppends) NameAppend { return n.Summary }
+)
+
+// join 按命名方式拼接完整指标名, name 需要已经转义
+func (s NamingScheme) join(namespace, subsystem string, na NameAppend, name string) string {
+	if s == NamingUnderscore || s == NamingDual {
+		if !strings.HasSuffix(name, na.Suffix) {
+			name += na.Suffix
+		}
+		names := iters.Of(namespace, subsystem, na.Prefix+name).
+			Filter(values.IsNotZero).
+			ToSlice()
+		return strings.ReplaceAll(strings.Join(names, "_"), ":", "_")
+	}
+	names := iters.Of(namespace, subsystem, na.Prefix+name+na.Suffix).
+		Filter(values.IsNotZero).
+		ToSlice()
+	return strings.Join(names, ":")
+}
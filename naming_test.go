@@ -0,0 +1,90 @@
+package monitor_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"code.gopub.tech/commons/assert"
+	"code.gopub.tech/monitor"
+)
+
+func TestNamingScheme(t *testing.T) {
+	record := func(opts ...monitor.Opt) string {
+		c := monitor.NewClient(append([]monitor.Opt{monitor.WithNamespace("app")}, opts...)...)
+		c.Record(ctx, "req", "请求数")
+		c.Record(ctx, "done_total", "完成数")
+		c.Sub("db").Sub("pool").Cost(ctx, "query", "查询耗时", time.Millisecond)
+		c.Store(ctx, "conns", "连接数", 3)
+		w := httptest.NewRecorder()
+		c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		t.Log(w.Body.String())
+		return w.Body.String()
+	}
+
+	body := record()
+	assert.True(t, strings.Contains(body, "\napp:counter:req 1\n"))
+	assert.True(t, strings.Contains(body, "\napp:counter:done_total 1\n"))
+	assert.True(t, strings.Contains(body, "\napp:db:pool:timer:query_seconds_count 1\n"))
+	assert.True(t, strings.Contains(body, "\napp:gauge:conns 3\n"))
+
+	body = record(monitor.WithNamingScheme(monitor.NamingUnderscore))
+	assert.True(t, !strings.Contains(body, "app:"), body)
+	assert.True(t, strings.Contains(body, "\napp_req_total 1\n"))
+	assert.True(t, strings.Contains(body, "\napp_done_total 1\n"))
+	assert.True(t, strings.Contains(body, "\napp_db_pool_query_seconds_count 1\n"))
+	assert.True(t, strings.Contains(body, "\napp_conns 3\n"))
+
+	body = record(monitor.WithNamingScheme(monitor.NamingDual))
+	assert.True(t, strings.Contains(body, "\napp_req_total 1\n"))
+	assert.True(t, strings.Contains(body, "\napp:counter:req 1\n"))
+	assert.True(t, strings.Contains(body, "\napp_db_pool_query_seconds_count 1\n"))
+	assert.True(t, strings.Contains(body, "\napp:db:pool:timer:query_seconds_count 1\n"))
+	assert.True(t, strings.Contains(body, "\napp_conns 3\n"))
+	assert.True(t, strings.Contains(body, "\napp:gauge:conns 3\n"))
+
+	// 自定义前缀/后缀时不使用命名方式的默认值
+	body = record(monitor.WithNamingScheme(monitor.NamingUnderscore),
+		monitor.WithNameAppend(monitor.NameAppends{Counter: monitor.NameAppend{Prefix: "c_"}}))
+	assert.True(t, strings.Contains(body, "\napp_c_req 1\n"))
+}
+
+func TestNamingSchemeConfig(t *testing.T) {
+	cfg, err := monitor.LoadConfig(strings.NewReader("naming_scheme: underscore\n"))
+	assert.True(t, err == nil, err)
+	assert.True(t, cfg.NameAppends.Counter.Suffix == "_total" && cfg.NameAppends.Gauge.Prefix == "", cfg)
+
+	_, err = monitor.LoadConfig(strings.NewReader("naming_scheme: dots\n"))
+	assert.True(t, err != nil && strings.Contains(err.Error(), "naming_scheme"), err)
+
+	t.Setenv("NAMING_MONITOR_NAMING_SCHEME", "underscore")
+	c := monitor.NewClient(monitor.WithNamespace("app"), monitor.WithEnv("NAMING_MONITOR"))
+	c.Record(ctx, "req", "请求数")
+	c.Sub("db").Record(ctx, "query", "查询数")
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	assert.True(t, strings.Contains(body, "\napp_req_total 1\n"), body)
+	assert.True(t, strings.Contains(body, "\napp_db_query_total 1\n"), body)
+}
+
+func TestNamingDualSink(t *testing.T) {
+	var names []string
+	c := monitor.NewClient(monitor.WithNamespace("app"), monitor.WithNamingScheme(monitor.NamingDual),
+		monitor.WithSinks(monitor.PrometheusSink, monitor.SinkFunc(func(_ context.Context, e monitor.Event) {
+			names = append(names, e.Name)
+		})))
+	c.Record(ctx, "req", "请求数")
+	c.Store(ctx, "conns", "连接数", 3)
+	// 其他 Sink 每次打点只收到一个事件
+	assert.DeepEqual(t, names, []string{"app_req_total", "app_conns"})
+
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	assert.True(t, strings.Contains(body, "\napp_req_total 1\n"), body)
+	assert.True(t, strings.Contains(body, "\napp:counter:req 1\n"), body)
+	assert.True(t, strings.Contains(body, "\napp:gauge:conns 3\n"), body)
+}
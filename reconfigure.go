@@ -0,0 +1,341 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"code.gopub.tech/commons/syncs"
+)
+
+// tuning 运行时可以调整的配置, 参见 Reconfigure
+type tuning struct {
+	buckets    []float64
+	objectives map[float64]float64
+	overrides  []metricOverride
+	enabled    []string
+	disabled   []string
+
+	overrideMatchers []metricMatcher // 与 overrides 一一对应
+	enabledMatchers  []metricMatcher
+	disabledMatchers []metricMatcher
+	resolved         *syncs.Map[string, resolution] // 完整指标名 => 匹配结果
+}
+
+// metricOverride 覆盖指定指标的默认分布/分位数
+type metricOverride struct {
+	name       string // 指标名模式, 参见 compileMatcher
+	buckets    []float64
+	objectives map[float64]float64
+}
+
+// resolution 一个指标的匹配结果
+type resolution struct {
+	allow      bool
+	buckets    []float64
+	objectives map[float64]float64
+}
+
+// newTuning 编译指标名模式, 模式无效时返回错误
+func (c *client) newTuning() (*tuning, error) {
+	t := &tuning{
+		buckets:    c.buckets,
+		objectives: c.objectives,
+		overrides:  c.overrides,
+		enabled:    c.enabled,
+		disabled:   c.disabled,
+		resolved:   syncs.NewMap[string, resolution](),
+	}
+	var errs []error
+	for _, o := range c.overrides {
+		m, err := compileMatcher(o.name)
+		if err != nil {
+			errs = append(errs, err)
+			m = func(Event) bool { return false }
+		}
+		t.overrideMatchers = append(t.overrideMatchers, m)
+	}
+	var err error
+	if t.enabledMatchers, err = compileMatchers(c.enabled); err != nil {
+		errs = append(errs, err)
+	}
+	if t.disabledMatchers, err = compileMatchers(c.disabled); err != nil {
+		errs = append(errs, err)
+	}
+	return t, errors.Join(errs...)
+}
+
+// resolve 返回指标是否记录及默认的分布/分位数, 结果按完整指标名缓存
+func (t *tuning) resolve(e Event) resolution {
+	if r, ok := t.resolved.Load(e.Name); ok {
+		return r
+	}
+	r, _ := t.resolved.LoadOrStore(e.Name, resolution{
+		allow:      t.allow(e),
+		buckets:    t.bucketsFor(e),
+		objectives: t.objectivesFor(e),
+	})
+	return r
+}
+
+// allow 是否记录该指标, 禁用优先
+func (t *tuning) allow(e Event) bool {
+	if matchAny(t.disabledMatchers, e) {
+		return false
+	}
+	return len(t.enabledMatchers) == 0 || matchAny(t.enabledMatchers, e)
+}
+
+// bucketsFor 返回指标的默认分布, 后添加的覆盖配置优先
+func (t *tuning) bucketsFor(e Event) []float64 {
+	for i, o := range slices.Backward(t.overrides) {
+		if len(o.buckets) > 0 && t.overrideMatchers[i](e) {
+			return o.buckets
+		}
+	}
+	return t.buckets
+}
+
+// objectivesFor 返回指标的默认分位数, 后添加的覆盖配置优先
+func (t *tuning) objectivesFor(e Event) map[float64]float64 {
+	for i, o := range slices.Backward(t.overrides) {
+		if o.objectives != nil && t.overrideMatchers[i](e) {
+			return o.objectives
+		}
+	}
+	return t.objectives
+}
+
+// apply 为未指定分布/分位数的打点事件填充默认值; 指标被禁用时返回 false
+func (t *tuning) apply(e Event) (Event, bool) {
+	r := t.resolve(e)
+	if !r.allow {
+		return e, false
+	}
+	switch {
+	case e.Kind == KindHistogram && (len(e.Buckets) == 0 || e.tuning != nil):
+		e.Buckets = r.buckets
+		e.tuning = t
+	case e.Kind == KindSummary && (e.Objectives == nil || e.tuning != nil):
+		e.Objectives = r.objectives
+		e.tuning = t
+	}
+	return e, true
+}
+
+// WithMetricBuckets 设置匹配 pattern 的指标的默认分布, 覆盖 WithBuckets
+//
+// pattern 与调用时传入的指标名或完整指标名匹配即可, 可以是:
+// 指标名; glob (包含 * ? [ 时, 参见 [path.Match]); 以 re: 开头的正则表达式 (部分匹配, 参见 [regexp.Regexp.MatchString]).
+// 多个模式都匹配时, 后添加的优先; 同一个模式重复设置时覆盖之前的设置, buckets 为空表示移除该模式的覆盖配置.
+// 仅在打点时未指定分布时生效(如 Cost, Timer()), 调用时指定的分布优先. 模式无效时通过 logger 输出, 并且不会匹配任何指标.
+//
+//	monitor.WithMetricBuckets("db_*", .001, .005, .01, .05, .1)        // 毫秒级
+//	monitor.WithMetricBuckets("re:^batch_", 60, 300, 900, 1800, 3600) // 分钟级
+func WithMetricBuckets(pattern string, buckets ...float64) Opt {
+	return func(c *client) {
+		c.overrides = setOverride(c.overrides, pattern, func(o *metricOverride) { o.buckets = buckets })
+	}
+}
+
+// WithMetricObjectives 设置匹配 pattern 的指标的默认分位数, 覆盖 WithObjectives
+// pattern 同 WithMetricBuckets; objectives 为 nil 表示移除该模式的覆盖配置.
+func WithMetricObjectives(pattern string, objectives map[float64]float64) Opt {
+	return func(c *client) {
+		c.overrides = setOverride(c.overrides, pattern, func(o *metricOverride) { o.objectives = objectives })
+	}
+}
+
+// setOverride 修改模式 name 的覆盖配置(返回新的切片, 不修改原切片), 分布/分位数都为空时移除
+func setOverride(overrides []metricOverride, name string, set func(*metricOverride)) []metricOverride {
+	overrides = slices.Clone(overrides)
+	i := slices.IndexFunc(overrides, func(o metricOverride) bool { return o.name == name })
+	if i < 0 {
+		overrides = append(overrides, metricOverride{name: name})
+		i = len(overrides) - 1
+	}
+	set(&overrides[i])
+	if len(overrides[i].buckets) == 0 && overrides[i].objectives == nil {
+		overrides = slices.Delete(overrides, i, i+1)
+	}
+	return overrides
+}
+
+// WithEnabledMetrics 只记录匹配的指标, 其他指标的打点直接丢弃
+// patterns 同 WithMetricBuckets; 不传表示记录所有指标(默认值). 每次调用都会替换之前的设置.
+func WithEnabledMetrics(patterns ...string) Opt {
+	return func(c *client) {
+		c.enabled = patterns
+	}
+}
+
+// WithDisabledMetrics 不记录匹配的指标, 优先于 WithEnabledMetrics
+// patterns 同 WithMetricBuckets. 每次调用都会替换之前的设置.
+func WithDisabledMetrics(patterns ...string) Opt {
+	return func(c *client) {
+		c.disabled = patterns
+	}
+}
+
+// Reconfigure 运行时修改配置, 返回被重建(或因禁用被移除)的指标名
+//
+// 只有 WithBuckets, WithObjectives, WithMetricBuckets, WithMetricObjectives,
+// WithEnabledMetrics, WithDisabledMetrics 选项生效, 它们在当前配置的基础上修改;
+// 修改命名, 标签, registry 或注册后台任务的选项, 以及无效的指标名模式会返回错误, 且不做任何修改.
+//
+// 已经写入 registry 的指标, 若使用的默认分布/分位数发生了变化, 会被注销并在下次打点时按新的配置重新创建
+// (已记录的数据会丢失); 被禁用的指标会被注销. 打点调用时指定的分布/分位数不受影响.
+// 重建期间的打点会等待重建完成, 不会写入旧的指标.
+//
+// 只影响当前 client 首次写入的指标, 已经派生的子 client 及其指标不受影响(之后派生的子 client 会继承新的配置).
+//
+//	rebuilt, err := c.Reconfigure(monitor.WithBuckets([]float64{.01, .1, 1}))
+func (c *client) Reconfigure(opts ...Opt) ([]string, error) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+	old := c.tuning.Load()
+	tmp := *c
+	tmp.starters, tmp.server = nil, nil
+	tmp.buckets, tmp.objectives = old.buckets, old.objectives
+	tmp.overrides, tmp.enabled, tmp.disabled = old.overrides, old.enabled, old.disabled
+	for _, opt := range opts {
+		opt(&tmp)
+	}
+	if tmp.namespace != c.namespace || tmp.subsystem != c.subsystem || tmp.names != c.names || tmp.naming != c.naming || tmp.timeUnit != c.timeUnit ||
+		tmp.registry != c.registry || !maps.Equal(tmp.constLabels, c.constLabels) ||
+		!maps.Equal(tmp.labels, c.labels) || tmp.utf8Names != c.utf8Names || tmp.escaping != c.escaping || len(tmp.starters) > 0 {
+		return nil, errors.New("monitor: Reconfigure only accepts bucket, objective and metric filter options")
+	}
+	if len(tmp.buckets) == 0 {
+		tmp.buckets = defaultBuckets(tmp.timeUnit)
+	}
+	if len(tmp.objectives) == 0 {
+		tmp.objectives = map[float64]float64{}
+	}
+	t, err := tmp.newTuning()
+	if err != nil {
+		return nil, fmt.Errorf("monitor: Reconfigure: %w", err)
+	}
+
+	var rebuilt []string
+	c.seen.Range(func(name string, info *metricInfo) bool {
+		if info.owner != c.tuning {
+			return true // 共享指标缓存的父/子 client 写入的指标
+		}
+		probe := Event{Metric: info.metric, Name: name}
+		changed := !t.allow(probe)
+		if !changed && info.defaulted {
+			if _, ok := c.histogram.Load(name); ok {
+				changed = !slices.Equal(old.bucketsFor(probe), t.bucketsFor(probe))
+			} else if _, ok := c.summary.Load(name); ok {
+				changed = !maps.Equal(old.objectivesFor(probe), t.objectivesFor(probe))
+			}
+		}
+		if changed {
+			c.unregisterMetric(name)
+			rebuilt = append(rebuilt, name)
+		}
+		return true
+	})
+	c.tuning.Store(t)
+	slices.Sort(rebuilt)
+	return rebuilt, nil
+}
+
+// unregisterMetric 从 registry 及缓存中移除指标, 需要持有 cacheMu 写锁
+func (c *client) unregisterMetric(name string) {
+	if v, ok := c.counter.Load(name); ok {
+		c.registry.Unregister(v)
+		c.counter.Delete(name)
+	}
+	if v, ok := c.gauge.Load(name); ok {
+		c.registry.Unregister(v)
+		c.gauge.Delete(name)
+	}
+	if v, ok := c.histogram.Load(name); ok {
+		c.registry.Unregister(v.Val1)
+		c.histogram.Delete(name)
+	}
+	if v, ok := c.summary.Load(name); ok {
+		c.registry.Unregister(v.Val1)
+		c.summary.Delete(name)
+	}
+	c.seen.Delete(name)
+}
+
+// WithReconfigureFile 从配置文件读取运行时配置, 并每隔 interval 检查文件是否有修改, 有修改时调用 Reconfigure
+//
+// 配置文件格式同 LoadConfig, 只有 buckets, objectives, metrics, enabled_metrics, disabled_metrics 生效,
+// 文件中没有的项使用构造 client 时的配置.
+// 读取或校验失败时保持当前配置, 并通过 logger 输出. 需要调用 Close 停止.
+// interval 不是正数时通过 logger 输出错误, 只在构造时读取一次.
+//
+//	c := monitor.NewClient(monitor.WithReconfigureFile("/etc/app/monitor.yaml", time.Minute))
+//	defer c.Close()
+func WithReconfigureFile(filename string, interval time.Duration) Opt {
+	return func(c *client) {
+		c.starters = append(c.starters, func(c *client) {
+			base := c.tuning.Load() // 配置文件中没有的项使用构造时的配置
+			reset := func(c *client) {
+				c.buckets, c.objectives = base.buckets, base.objectives
+				c.overrides, c.enabled, c.disabled = base.overrides, base.enabled, base.disabled
+			}
+			var lastMod time.Time
+			var lastSize int64 = -1
+			reload := func() {
+				ctx := context.Background()
+				info, err := os.Stat(filename)
+				if err != nil {
+					c.recordErr(filename, "reconfigure_file")
+					c.logger(ctx, "reconfigure_file|StatFailed", "filename", filename, "err", err)
+					return
+				}
+				if info.ModTime().Equal(lastMod) && info.Size() == lastSize {
+					return
+				}
+				lastMod, lastSize = info.ModTime(), info.Size()
+				f, err := os.Open(filename)
+				if err != nil {
+					c.recordErr(filename, "reconfigure_file")
+					c.logger(ctx, "reconfigure_file|OpenFailed", "filename", filename, "err", err)
+					return
+				}
+				defer f.Close()
+				cfg, err := LoadConfig(f)
+				if err == nil {
+					var rebuilt []string
+					rebuilt, err = c.Reconfigure(append([]Opt{reset}, cfg.TuningOpts()...)...)
+					if err == nil {
+						if len(rebuilt) > 0 {
+							c.logger(ctx, "reconfigure_file|Rebuilt", "filename", filename, "metrics", rebuilt)
+						}
+						return
+					}
+				}
+				c.recordErr(filename, "reconfigure_file")
+				c.logger(ctx, "reconfigure_file|ReconfigureFailed", "filename", filename, "err", err)
+			}
+			reload()
+			if interval <= 0 {
+				c.logger(context.Background(), "reconfigure_file|InvalidInterval", "filename", filename, "interval", interval)
+				return // 只在构造时读取一次
+			}
+			c.life.goBackground(func(done <-chan struct{}) {
+				ticker := time.NewTicker(interval)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-done:
+						return
+					case <-ticker.C:
+						reload()
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package monitor_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"code.gopub.tech/commons/assert"
+	"code.gopub.tech/monitor"
+)
+
+func TestReconfigure(t *testing.T) {
+	c := monitor.NewClient(
+		monitor.WithBuckets([]float64{1}),
+		monitor.WithMetricBuckets("db_query", .01, .1),
+	)
+	scrape := func() string {
+		w := httptest.NewRecorder()
+		c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		return w.Body.String()
+	}
+	c.Cost(ctx, "db_query", "查询耗时", time.Millisecond*5)
+	c.Cost(ctx, "api", "接口耗时", time.Millisecond*5)
+	c.CostBuckets(ctx, "fixed", "固定分布", time.Millisecond*5, []time.Duration{time.Second * 3})
+	c.Record(ctx, "noisy", "调试计数")
+	body := scrape()
+	assert.True(t, strings.Contains(body, `timer:db_query_seconds_bucket{le="0.01"} 1`))
+	assert.True(t, strings.Contains(body, `timer:api_seconds_bucket{le="1"} 1`))
+
+	// 只重建使用默认分布的指标
+	rebuilt, err := c.Reconfigure(monitor.WithBuckets([]float64{.5, 2}))
+	assert.True(t, err == nil, err)
+	assert.DeepEqual(t, rebuilt, []string{"timer:api_seconds"})
+	c.Cost(ctx, "api", "接口耗时", time.Second)
+	c.CostBuckets(ctx, "fixed", "固定分布", time.Millisecond*5, []time.Duration{time.Second * 3})
+	body = scrape()
+	t.Log(body)
+	assert.True(t, strings.Contains(body, `timer:api_seconds_bucket{le="2"} 1`))
+	assert.True(t, strings.Contains(body, `timer:api_seconds_count 1`))
+	assert.True(t, strings.Contains(body, `timer:fixed_seconds_count 2`))
+	assert.True(t, strings.Contains(body, `timer:db_query_seconds_count 1`))
+
+	// 覆盖配置及禁用
+	rebuilt, err = c.Reconfigure(
+		monitor.WithMetricBuckets("db_query", .001),
+		monitor.WithDisabledMetrics("noisy"),
+	)
+	assert.True(t, err == nil, err)
+	assert.DeepEqual(t, rebuilt, []string{"counter:noisy", "timer:db_query_seconds"})
+	c.Record(ctx, "noisy", "调试计数")
+	c.Cost(ctx, "db_query", "查询耗时", time.Millisecond*5)
+	body = scrape()
+	assert.True(t, !strings.Contains(body, "counter:noisy"))
+	assert.True(t, strings.Contains(body, `timer:db_query_seconds_bucket{le="0.001"} 0`))
+
+	// 只记录指定的指标
+	rebuilt, err = c.Reconfigure(monitor.WithDisabledMetrics(), monitor.WithEnabledMetrics("noisy", "timer:api_seconds"))
+	assert.True(t, err == nil, err)
+	assert.DeepEqual(t, rebuilt, []string{"timer:db_query_seconds", "timer:fixed_seconds"})
+	c.Record(ctx, "noisy", "调试计数")
+	body = scrape()
+	assert.True(t, strings.Contains(body, "counter:noisy 1"))
+	assert.True(t, strings.Contains(body, "timer:api_seconds"))
+	assert.True(t, !strings.Contains(body, "timer:db_query_seconds"))
+
+	// 其他选项不能在运行时修改
+	_, err = c.Reconfigure(monitor.WithNamespace("other"))
+	assert.True(t, err != nil)
+}
+
+func TestReconfigureConcurrent(t *testing.T) {
+	c := monitor.NewClient()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				c.Cost(ctx, "api", "接口耗时", time.Millisecond)
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		_, err := c.Reconfigure(monitor.WithBuckets([]float64{float64(i + 1)}))
+		assert.True(t, err == nil, err)
+	}
+	wg.Wait()
+	// 最终只有一个指标, 且使用最后的分布
+	families, err := c.Registry().Gather()
+	assert.True(t, err == nil, err)
+	for _, mf := range families {
+		if mf.GetName() == "timer:api_seconds" {
+			buckets := mf.GetMetric()[0].GetHistogram().GetBucket()
+			assert.True(t, len(buckets) == 1 && buckets[0].GetUpperBound() == 20, buckets)
+		}
+	}
+}
+
+func TestWithReconfigureFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "monitor.yaml")
+	assert.True(t, os.WriteFile(filename, []byte("buckets: [1]\n"), 0o644) == nil)
+	c := monitor.NewClient(monitor.WithReconfigureFile(filename, time.Millisecond*10))
+	defer c.Close()
+	scrape := func() string {
+		w := httptest.NewRecorder()
+		c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		return w.Body.String()
+	}
+	c.Cost(ctx, "api", "接口耗时", time.Millisecond)
+	assert.True(t, strings.Contains(scrape(), `timer:api_seconds_bucket{le="1"} 1`))
+
+	assert.True(t, os.WriteFile(filename, []byte("metrics:\n  - {name: api, buckets: [0.5, 3]}\n"), 0o644) == nil)
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		c.Cost(ctx, "api", "接口耗时", time.Millisecond)
+		if strings.Contains(scrape(), `timer:api_seconds_bucket{le="3"}`) {
+			break
+		}
+		assert.True(t, time.Now().Before(deadline), "config file not reloaded")
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestReconfigureOwnMetrics(t *testing.T) {
+	c := monitor.NewClient(monitor.WithBuckets([]float64{1}))
+	sub := c.Sub("db")
+	sub.Cost(ctx, "query", "查询耗时", time.Millisecond)
+	c.Cost(ctx, "api", "接口耗时", time.Millisecond)
+
+	// 子 client 的指标不会被重建
+	rebuilt, err := c.Reconfigure(monitor.WithBuckets([]float64{.5, 2}))
+	assert.True(t, err == nil, err)
+	assert.DeepEqual(t, rebuilt, []string{"timer:api_seconds"})
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.True(t, strings.Contains(w.Body.String(), `db:timer:query_seconds_bucket{le="1"} 1`), w.Body.String())
+}
+
+func TestWithReconfigureFileInvalidInterval(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "monitor.yaml")
+	assert.True(t, os.WriteFile(filename, []byte("buckets: [1]\n"), 0o644) == nil)
+	var logs []string
+	c := monitor.NewClient(
+		monitor.WithLogger(func(_ context.Context, msg string, _ ...any) { logs = append(logs, msg) }),
+		monitor.WithReconfigureFile(filename, 0),
+	)
+	defer c.Close()
+	assert.DeepEqual(t, logs, []string{"reconfigure_file|InvalidInterval"})
+	c.Cost(ctx, "api", "接口耗时", time.Millisecond)
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.True(t, strings.Contains(w.Body.String(), `timer:api_seconds_bucket{le="1"} 1`))
+}
@@ -0,0 +1,108 @@
+package monitor
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RoundTripper 返回记录出站 HTTP 请求指标的 http.RoundTripper
+// base 为 nil 时使用 [http.DefaultTransport].
+//
+// 标签为 host, method, status 以及请求 ctx 上的标签(与 CtxGetLabels 一致).
+// status 在请求成功时是 HTTP 状态码, 失败时是错误分类:
+// canceled, timeout, connection_refused, connection_reset, dns, tls, eof, error.
+// 通过 httptrace 记录 DNS 解析, 建立连接, TLS 握手, 首字节时间各阶段耗时, 使用 phase 标签区分.
+//
+//	// namespace:subsystem:counter:http_client_requests
+//	// namespace:subsystem:timer:http_client_request_duration_seconds
+//	// namespace:subsystem:timer:http_client_phase_duration_seconds
+//	hc := &http.Client{Transport: c.RoundTripper(nil)}
+func (c *client) RoundTripper(base http.RoundTripper, opts ...HTTPOpt) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &roundTripper{c: c, base: base, hc: c.newHTTPConfig("http_client", opts...)}
+}
+
+type roundTripper struct {
+	c    *client
+	base http.RoundTripper
+	hc   *httpConfig
+}
+
+func (t *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	phases := &tracePhases{start: time.Now()}
+	req = req.WithContext(httptrace.WithClientTrace(ctx, phases.trace()))
+	resp, err := t.base.RoundTrip(req)
+	cost := time.Since(phases.start)
+
+	var status string
+	if err != nil {
+		status = t.c.classify(err)
+	} else {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	kvs := []string{"host", req.URL.Host, "method", req.Method}
+	c, name := t.c, t.hc.name
+	c.Record(ctx, name+"_requests", "出站 HTTP 请求数", append(kvs, "status", status)...)
+	c.recordHistogram(ctx, name+"_request_duration", "出站 HTTP 请求耗时", timerNames,
+		c.timeValue(cost), t.hc.buckets, append(kvs, "status", status)...)
+	for phase, d := range phases.durations() {
+		c.recordHistogram(ctx, name+"_phase_duration", "出站 HTTP 请求各阶段耗时", timerNames,
+			c.timeValue(d), t.hc.buckets, append(kvs, "phase", phase)...)
+	}
+	return resp, err
+}
+
+// tracePhases 记录请求各阶段的时间点
+// httptrace 的回调可能并发执行(如同时尝试多个地址建立连接), 因此需要加锁
+type tracePhases struct {
+	mu                        sync.Mutex
+	start                     time.Time
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	tlsStart, tlsDone         time.Time
+	firstByte                 time.Time
+}
+
+func (p *tracePhases) set(t *time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if t.IsZero() {
+		*t = time.Now()
+	}
+}
+
+func (p *tracePhases) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { p.set(&p.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { p.set(&p.dnsDone) },
+		ConnectStart:         func(string, string) { p.set(&p.connectStart) },
+		ConnectDone:          func(string, string, error) { p.set(&p.connectDone) },
+		TLSHandshakeStart:    func() { p.set(&p.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { p.set(&p.tlsDone) },
+		GotFirstResponseByte: func() { p.set(&p.firstByte) },
+	}
+}
+
+// durations 返回已完成的阶段耗时, 复用连接时没有 dns/connect/tls 阶段
+func (p *tracePhases) durations() map[string]time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	result := map[string]time.Duration{}
+	add := func(phase string, start, end time.Time) {
+		if !start.IsZero() && !end.IsZero() {
+			result[phase] = end.Sub(start)
+		}
+	}
+	add("dns", p.dnsStart, p.dnsDone)
+	add("connect", p.connectStart, p.connectDone)
+	add("tls", p.tlsStart, p.tlsDone)
+	add("ttfb", p.start, p.firstByte)
+	return result
+}
@@ -0,0 +1,54 @@
+package monitor_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"code.gopub.tech/commons/assert"
+	"code.gopub.tech/monitor"
+)
+
+func TestRoundTripper(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	// 拿到一个没有监听的端口
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	refused := ln.Addr().String()
+	ln.Close()
+
+	c := monitor.NewClient()
+	hc := &http.Client{Transport: c.RoundTripper(nil)}
+	reqCtx := monitor.CtxAddLabels(ctx, "caller", "test")
+
+	req, _ := http.NewRequestWithContext(reqCtx, "GET", srv.URL, nil)
+	resp, err := hc.Do(req)
+	assert.True(t, err == nil)
+	resp.Body.Close()
+
+	req, _ = http.NewRequestWithContext(reqCtx, "GET", "http://"+refused, nil)
+	_, err = hc.Do(req)
+	assert.True(t, err != nil)
+
+	canceled, cancel := context.WithCancel(reqCtx)
+	cancel()
+	req, _ = http.NewRequestWithContext(canceled, "GET", srv.URL, nil)
+	_, err = hc.Do(req)
+	assert.True(t, err != nil)
+
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	t.Log(body)
+	assert.True(t, strings.Contains(body, `counter:http_client_requests{caller="test",host="`+host+`",method="GET",status="418"} 1`))
+	assert.True(t, strings.Contains(body, `counter:http_client_requests{caller="test",host="`+refused+`",method="GET",status="connection_refused"} 1`))
+	assert.True(t, strings.Contains(body, `counter:http_client_requests{caller="test",host="`+host+`",method="GET",status="canceled"} 1`))
+	assert.True(t, strings.Contains(body, `timer:http_client_phase_duration_seconds_count{caller="test",host="`+host+`",method="GET",phase="ttfb"} 1`))
+}
@@ -0,0 +1,54 @@
+package monitor
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// WithStrictSchema 拒绝与已注册指标的类型, 说明或标签名不一致的打点
+//
+// 同一个完整指标名只会注册一次, 之后的打点沿用首次注册时的类型, 说明及标签名.
+// 不论是否开启, 不一致时都会通过 logger 输出双方的调用位置(每种不一致只输出一次),
+// 并记录到内部异常指标中(kind 为 schema_conflict); 开启后不一致的打点会被丢弃, 否则仍按原来的方式写入.
+// 默认不开启
+func WithStrictSchema(strict bool) Opt {
+	return func(c *client) {
+		c.strictSchema = strict
+	}
+}
+
+// checkSchema 检查打点事件与已注册指标是否一致, 返回 false 表示拒绝本次打点
+func (c *client) checkSchema(ctx context.Context, e Event) bool {
+	info, ok := c.seen.Load(e.Name)
+	if !ok {
+		return true
+	}
+	var conflicts []string
+	if info.kind != e.Kind {
+		conflicts = append(conflicts, "kind")
+	}
+	if info.help != e.Help {
+		conflicts = append(conflicts, "help")
+	}
+	if len(info.labels) != len(e.Labels) || slices.ContainsFunc(info.labels, func(name string) bool {
+		_, ok := e.Labels[name]
+		return !ok
+	}) {
+		conflicts = append(conflicts, "labels")
+	}
+	if len(conflicts) == 0 {
+		return true
+	}
+	c.recordErr(e.Name, "schema_conflict")
+	labels := e.labelNames()
+	key := strings.Join([]string{string(e.Kind), e.Help, strings.Join(labels, ",")}, "\xff")
+	if _, reported := info.conflicts.LoadOrStore(key, struct{}{}); !reported {
+		c.logger(ctx, "schema_conflict",
+			"name", e.Name, "conflicts", conflicts, "strict", c.strictSchema,
+			"kind", e.Kind, "help", e.Help, "labels", labels, "callSite", e.site(),
+			"firstKind", info.kind, "firstHelp", info.help, "firstLabels", info.labels, "firstCallSite", info.callSite,
+		)
+	}
+	return !c.strictSchema
+}
@@ -0,0 +1,52 @@
+package monitor_test
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"code.gopub.tech/commons/assert"
+	"code.gopub.tech/monitor"
+)
+
+func TestSchemaConflict(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		var logs []string
+		c := monitor.NewClient(
+			monitor.WithNameAppend(monitor.NameAppends{Timer: monitor.NameAppend{Suffix: "_seconds"}}),
+			monitor.WithStrictSchema(strict),
+			monitor.WithLogger(func(_ context.Context, msg string, args ...any) {
+				logs = append(logs, msg+fmt.Sprint(args...))
+			}),
+		)
+		c.Record(ctx, "jobs", "任务数")
+		c.Record(ctx, "jobs", "任务总数")  // help 不一致
+		c.Record(ctx, "jobs", "任务总数")  // 同一种不一致只输出一次
+		c.Store(ctx, "jobs", "任务数", 5) // 类型不一致
+		c.Record(ctx, "jobs", "任务数", "k", "v")
+
+		schemaLogs := 0
+		for _, log := range logs {
+			if strings.HasPrefix(log, "schema_conflict") {
+				schemaLogs++
+				assert.True(t, strings.Count(log, "schema_test.go:") == 2, log) // 双方的调用位置
+			}
+		}
+		assert.True(t, schemaLogs == 3, logs)
+
+		w := httptest.NewRecorder()
+		c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		body := w.Body.String()
+		t.Log(body)
+		assert.True(t, strings.Contains(body, `internal_monitor_error{kind="schema_conflict",name="jobs"} 4`))
+		if strict {
+			assert.True(t, strings.Contains(body, "\njobs 1\n"))
+			assert.True(t, !strings.Contains(body, "register_gauge"))
+		} else {
+			assert.True(t, strings.Contains(body, "\njobs 3\n"))
+			assert.True(t, strings.Contains(body, `kind="register_gauge_dup",name="jobs"`))
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"strings"
+	"time"
+)
+
+// UNIX_PREFIX 监听地址以此开头时, 表示监听 unix socket
+const UNIX_PREFIX = "unix:"
+
+// ServeOpt 独立指标服务的配置项
+type ServeOpt func(*serveConfig)
+
+type serveConfig struct {
+	pattern         string
+	pprof           bool
+	ready           func(context.Context) error
+	certFile        string
+	keyFile         string
+	shutdownTimeout time.Duration
+}
+
+// ServePattern 设置暴露指标的路径
+// 默认值是 [PATTERN_METRICS]
+func ServePattern(pattern string) ServeOpt {
+	return func(sc *serveConfig) {
+		sc.pattern = pattern
+	}
+}
+
+// ServePprof 同时暴露 /debug/pprof/ 端点
+// 默认不暴露
+func ServePprof() ServeOpt {
+	return func(sc *serveConfig) {
+		sc.pprof = true
+	}
+}
+
+// ServeReadiness 设置 /readyz 端点的检查函数, 返回错误时响应 503
+// 默认值是 nil, 表示总是就绪. /healthz 端点总是响应 200
+func ServeReadiness(check func(context.Context) error) ServeOpt {
+	return func(sc *serveConfig) {
+		sc.ready = check
+	}
+}
+
+// ServeTLS 使用证书文件启用 HTTPS
+func ServeTLS(certFile, keyFile string) ServeOpt {
+	return func(sc *serveConfig) {
+		sc.certFile = certFile
+		sc.keyFile = keyFile
+	}
+}
+
+// ServeShutdownTimeout 设置优雅关闭的超时时间
+// 默认值是 5s
+func ServeShutdownTimeout(timeout time.Duration) ServeOpt {
+	return func(sc *serveConfig) {
+		sc.shutdownTimeout = timeout
+	}
+}
+
+// WithServer 构造 client 后在后台启动独立的 HTTP 服务暴露指标, Close 时优雅关闭
+// 启动或运行出错时通过 logger 输出. 多次设置时使用最后一次的配置, 派生的子 client 不会再次启动.
+// 通常通过配置文件或环境变量设置, 参见 Config.Server
+//
+//	c := monitor.NewClient(monitor.WithServer(monitor.ServerConfig{Addr: ":9100", Pprof: true}))
+//	defer c.Close()
+func WithServer(sc ServerConfig) Opt {
+	return func(c *client) {
+		if c.server == nil {
+			c.starters = append(c.starters, func(c *client) {
+				c.life.goBackground(func(done <-chan struct{}) {
+					ctx, cancel := context.WithCancel(context.Background())
+					defer cancel()
+					go func() {
+						select {
+						case <-done:
+							cancel()
+						case <-ctx.Done():
+						}
+					}()
+					addr := c.server.Addr
+					if err := c.Serve(ctx, addr, c.server.opts()...); err != nil {
+						c.logger(ctx, "serve|ServeFailed", "addr", addr, "err", err)
+					}
+				})
+			})
+		}
+		c.server = &sc
+	}
+}
+
+// ListenAndServe 启动独立的 HTTP 服务暴露指标, 直到服务出错
+// 参见 Serve
+func (c *client) ListenAndServe(addr string, opts ...ServeOpt) error {
+	return c.Serve(context.Background(), addr, opts...)
+}
+
+// Serve 启动独立的 HTTP 服务暴露指标, ctx 取消时优雅关闭并返回 nil
+//
+// addr 以 `unix:` 开头时监听 unix socket, 便于 sidecar 抓取.
+// 除指标路径外, 还会暴露 /healthz, /readyz 健康检查端点, 以及可选的 /debug/pprof/ 端点.
+//
+//	go c.Serve(ctx, ":9100", monitor.ServePprof())
+//	go c.Serve(ctx, "unix:/run/app/metrics.sock")
+func (c *client) Serve(ctx context.Context, addr string, opts ...ServeOpt) error {
+	sc := &serveConfig{
+		pattern:         PATTERN_METRICS,
+		shutdownTimeout: 5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(sc)
+	}
+	ln, err := listen(addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: c.serveMux(sc)}
+	errCh := make(chan error, 1)
+	go func() {
+		if sc.certFile != "" || sc.keyFile != "" {
+			errCh <- srv.ServeTLS(ln, sc.certFile, sc.keyFile)
+		} else {
+			errCh <- srv.Serve(ln)
+		}
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), sc.shutdownTimeout)
+		defer cancel()
+		err := srv.Shutdown(shutdownCtx)
+		if serveErr := <-errCh; !errors.Is(serveErr, http.ErrServerClosed) {
+			err = errors.Join(err, serveErr)
+		}
+		return err
+	}
+}
+
+func (c *client) serveMux(sc *serveConfig) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle(sc.pattern, c.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if sc.ready != nil {
+			if err := sc.ready(r.Context()); err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.Write([]byte("ok"))
+	})
+	if sc.pprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	return mux
+}
+
+// listen 监听 tcp 地址或 unix socket
+// unix socket 文件已存在时(如上次进程未正常退出)会先删除
+func listen(addr string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, UNIX_PREFIX)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	return net.Listen("unix", path)
+}
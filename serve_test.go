@@ -0,0 +1,103 @@
+package monitor_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"code.gopub.tech/commons/assert"
+	"code.gopub.tech/monitor"
+)
+
+func TestServe(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "metrics.sock")
+	c := monitor.NewClient()
+	c.Record(ctx, "xxx_throughput", "xxx总量")
+
+	ready := errors.New("not ready")
+	serveCtx, cancel := context.WithCancel(ctx)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.Serve(serveCtx, monitor.UNIX_PREFIX+sock,
+			monitor.ServePprof(),
+			monitor.ServeReadiness(func(context.Context) error { return ready }),
+		)
+	}()
+
+	hc := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", sock)
+		},
+	}}
+	get := func(path string) (int, string) {
+		var resp *http.Response
+		var err error
+		for i := 0; i < 50; i++ { // 等待服务启动
+			if resp, err = hc.Get("http://unix" + path); err == nil {
+				break
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+		assert.True(t, err == nil)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	code, body := get("/metrics")
+	assert.True(t, code == http.StatusOK)
+	assert.True(t, strings.Contains(body, "counter:xxx_throughput 1"))
+	code, _ = get("/healthz")
+	assert.True(t, code == http.StatusOK)
+	code, _ = get("/readyz")
+	assert.True(t, code == http.StatusServiceUnavailable)
+	code, _ = get("/debug/pprof/")
+	assert.True(t, code == http.StatusOK)
+
+	cancel()
+	assert.True(t, <-errCh == nil)
+}
+
+func TestWithServer(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "metrics.sock")
+	t.Setenv("MONITOR_SERVER_ADDR", monitor.UNIX_PREFIX+sock)
+	t.Setenv("MONITOR_SERVER_PATTERN", "/m")
+	c, err := monitor.NewClientFromConfig(strings.NewReader("namespace: app"), monitor.WithEnv("MONITOR"))
+	assert.True(t, err == nil, err)
+	c.Record(ctx, "req", "请求数")
+	child := c.Sub("db") // 子 client 不会再次启动服务
+	child.Record(ctx, "query", "查询数")
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", sock)
+		},
+	}}
+	var body string
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		resp, err := client.Get("http://unix/m")
+		if err == nil {
+			data, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			body = string(data)
+			break
+		}
+		assert.True(t, time.Now().Before(deadline), err)
+		time.Sleep(time.Millisecond * 10)
+	}
+	assert.True(t, strings.Contains(body, "app:counter:req 1"), body)
+	assert.True(t, strings.Contains(body, "app:db:counter:query 1"), body)
+
+	assert.True(t, c.Close() == nil)
+	_, err = os.Stat(sock)
+	assert.True(t, os.IsNotExist(err), err)
+}
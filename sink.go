@@ -0,0 +1,190 @@
+package monitor
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"code.gopub.tech/commons/nums"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Kind 指标类型
+type Kind string
+
+const (
+	KindCounter   Kind = "counter"
+	KindGauge     Kind = "gauge"
+	KindHistogram Kind = "histogram"
+	KindSummary   Kind = "summary"
+)
+
+// Event 一次打点记录
+//
+// 各个打点 API 会将调用参数整理为 Event, 再分发给 client 上配置的所有 Sink.
+type Event struct {
+	Kind        Kind                // 指标类型
+	Metric      string              // 调用时传入的指标名, 不含名称空间/子系统/前缀/后缀
+	Name        string              // 完整指标名 namespace:subsystem:<prefix><name><suffix>
+	Help        string              // 指标说明
+	ConstLabels map[string]string   // 常量标签
+	Labels      map[string]string   // 标签(ctx 上的标签 + 调用时传入的标签)
+	Value       float64             // 打点值
+	Add         bool                // 仅 gauge 类型: true 表示在当前值上累加 Value, false 表示设置为 Value
+	Buckets     []float64           // 仅 histogram 类型有值
+	Objectives  map[float64]float64 // 仅 summary 类型有值
+
+	tuning *tuning // 非 nil 表示 Buckets/Objectives 是按该配置填充的默认值
+	alias  string  // NamingDual 时的旧指标名, 由 prometheusSink 同时写入
+	async  bool    // 在异步模式的后台 goroutine 中分发, 取不到调用位置
+}
+
+// call 一次打点调用的参数, 由 event 整理为 Event
+// 异步模式下原样放入队列, 在后台 goroutine 中整理, 使打点调用方只承担入队的开销.
+type call struct {
+	kind        Kind
+	names       nameKind
+	name, desc  string
+	value       float64
+	add         bool
+	buckets     []float64
+	objectives  map[float64]float64
+	kvs         []string
+	noCtxLabels bool              // 不使用 ctx 上的标签, 参见 StartSpan
+	ctxLabels   map[string]string // ctx 上的标签, 由 emit 填充
+}
+
+// event 将打点调用整理为 Event: 拼接完整指标名, 合并标签
+func (c *client) event(cl call) Event {
+	e := newEvent(cl.kind, c.prometheusOpt(cl.name, cl.desc, cl.names), c.mergeLabels(cl.ctxLabels, cl.kvs...), cl.value)
+	e.Add, e.Buckets, e.Objectives = cl.add, cl.buckets, cl.objectives
+	return e
+}
+
+func newEvent(kind Kind, opt metricOpts, labels map[string]string, value nums.AnyNumber) Event {
+	return Event{
+		Kind:        kind,
+		Metric:      opt.metric,
+		Name:        opt.Name,
+		Help:        opt.Help,
+		ConstLabels: opt.ConstLabels,
+		Labels:      labels,
+		Value:       nums.To[float64](value),
+		alias:       opt.alias,
+	}
+}
+
+func (e Event) prometheusOpt() prometheus.Opts {
+	return prometheus.Opts{
+		Name:        e.Name,
+		Help:        e.Help,
+		ConstLabels: e.ConstLabels,
+	}
+}
+
+// labelNames 返回排序后的标签名
+func (e Event) labelNames() []string {
+	return slices.Sorted(maps.Keys(e.Labels))
+}
+
+// Sink 打点事件的接收者
+//
+// 可以通过 WithSinks 配置多个 Sink, 每次打点会依次分发给所有 Sink,
+// 用于将打点同时写入日志, StatsD, 测试桩等后端.
+// Write 默认在打点调用方的 goroutine 中同步执行; 开启 WithAsync 时在后台 goroutine 中执行,
+// 此时 ctx 是打点时传入的 ctx, 可能已经取消. 实现方需要保证并发安全且尽快返回,
+// 且不应修改 Event 中的 map/slice (它们在多个 Sink 间共享).
+type Sink interface {
+	Write(ctx context.Context, e Event)
+}
+
+// SinkFunc 将函数适配为 Sink
+type SinkFunc func(ctx context.Context, e Event)
+
+// Write 实现 Sink 接口
+func (f SinkFunc) Write(ctx context.Context, e Event) {
+	f(ctx, e)
+}
+
+// PrometheusSink 表示将打点写入 client 自身 registry 的 Sink, 是 WithSinks 的默认值.
+// 它只是一个占位符, 构造 client 时会替换为绑定了该 client 的实现.
+var PrometheusSink Sink = placeholderSink{}
+
+type placeholderSink struct{}
+
+func (placeholderSink) Write(context.Context, Event) {}
+
+// prometheusSink 将打点事件写入 client 的 registry
+type prometheusSink struct {
+	c *client
+}
+
+// Write NamingDual 时同时写入新旧两个指标名, 其他 Sink 只收到一个使用新指标名的事件
+func (s *prometheusSink) Write(ctx context.Context, e Event) {
+	s.c.cacheMu.RLock()
+	defer s.c.cacheMu.RUnlock()
+	if e.alias == "" {
+		s.write(ctx, e)
+		return
+	}
+	legacy := e
+	legacy.Name, legacy.alias, e.alias = e.alias, "", ""
+	s.write(ctx, e)
+	// 旧指标名不做命名检查, 但转义冲突及启用/禁用按旧指标名检查
+	if !s.c.checkEscaping(ctx, legacy) {
+		return
+	}
+	if legacy, ok := s.c.tuning.Load().apply(legacy); ok {
+		s.write(ctx, legacy)
+	}
+}
+
+// write 将一个指标名的打点事件写入 registry
+func (s *prometheusSink) write(ctx context.Context, e Event) {
+	if t := s.c.tuning.Load(); t != e.tuning {
+		// emit 之后 Reconfigure 了, 按新的配置重新检查, 避免用旧的配置重建指标
+		var ok bool
+		if e, ok = t.apply(e); !ok {
+			return
+		}
+	}
+	if !s.c.checkSchema(ctx, e) {
+		return
+	}
+	switch e.Kind {
+	case KindCounter:
+		s.c.writeCounter(ctx, e)
+	case KindGauge:
+		s.c.writeGauge(ctx, e)
+	case KindHistogram:
+		s.c.writeHistogram(ctx, e)
+	case KindSummary:
+		s.c.writeSummary(ctx, e)
+	}
+}
+
+// emit 检查指标名及标签并填充默认的分布/分位数后, 将打点事件分发给所有 Sink; 被禁用的指标直接丢弃
+func (c *client) emit(ctx context.Context, cl call) {
+	if !cl.noCtxLabels {
+		cl.ctxLabels, _ = ctx.Value(ctxKey{}).(map[string]string) // CtxAddLabels 每次都会新建 map, 可以直接引用
+	}
+	if c.async != nil && c.async.enqueue(c, ctx, cl) {
+		return
+	}
+	c.dispatch(ctx, c.event(cl))
+}
+
+// dispatch 同 emit, 异步模式下在后台 goroutine 中执行
+func (c *client) dispatch(ctx context.Context, e Event) {
+	e.Name = c.lintName(ctx, e)
+	if !c.checkLabels(ctx, e) || !c.checkEscaping(ctx, e) {
+		return
+	}
+	e, ok := c.tuning.Load().apply(e)
+	if !ok {
+		return
+	}
+	for _, sink := range c.sinks {
+		sink.Write(ctx, e)
+	}
+}
@@ -0,0 +1,67 @@
+package monitor_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"code.gopub.tech/commons/assert"
+	"code.gopub.tech/monitor"
+)
+
+type memSink struct {
+	mu     sync.Mutex
+	events []monitor.Event
+}
+
+func (s *memSink) Write(_ context.Context, e monitor.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, e)
+}
+
+func TestSinks(t *testing.T) {
+	mem := &memSink{}
+	c := monitor.NewClient(
+		monitor.WithNamespace("ns"),
+		monitor.WithSinks(monitor.PrometheusSink, mem),
+	)
+	ctx := monitor.CtxAddLabels(ctx, "k", "v")
+	c.Record(ctx, "xxx_throughput", "xxx总量")
+	c.Store(ctx, "xxx_current_value", "xxx当前值", 3)
+	c.Histogram(ctx, "xxx_value", "xxx分布", 1.5, []float64{1, 2})
+	c.Summary(ctx, "xxx_avg", "xxx平均", 2)
+
+	assert.True(t, len(mem.events) == 4)
+	assert.DeepEqual(t, mem.events[0], monitor.Event{
+		Kind:        monitor.KindCounter,
+		Metric:      "xxx_throughput",
+		Name:        "ns:counter:xxx_throughput",
+		Help:        "xxx总量",
+		ConstLabels: map[string]string{},
+		Labels:      map[string]string{"k": "v"},
+		Value:       1,
+	})
+	assert.True(t, mem.events[1].Kind == monitor.KindGauge)
+	assert.DeepEqual(t, mem.events[2].Buckets, []float64{1, 2})
+	assert.True(t, mem.events[3].Kind == monitor.KindSummary)
+
+	// PrometheusSink 仍然生效
+	families, err := c.Registry().Gather()
+	assert.True(t, err == nil)
+	assert.True(t, len(families) == 4)
+}
+
+func TestSinkOnly(t *testing.T) {
+	var count int
+	c := monitor.NewClient(monitor.WithSinks(monitor.SinkFunc(func(context.Context, monitor.Event) {
+		count++
+	})))
+	c.Record(ctx, "xxx_throughput", "xxx总量")
+	assert.True(t, count == 1)
+
+	// 未配置 PrometheusSink, 不会写入 registry
+	families, err := c.Registry().Gather()
+	assert.True(t, err == nil)
+	assert.True(t, len(families) == 0)
+}
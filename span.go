@@ -0,0 +1,58 @@
+package monitor
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type spanKey struct{}
+
+// span 一个计时阶段
+type span struct {
+	path string // 从根阶段开始的完整路径, 如 request/db/query
+}
+
+// StartSpan 开始一个计时阶段, 返回带有该阶段的 ctx 及结束函数(使用 Timer 指标前缀/后缀)
+//
+// 使用返回的 ctx 开始的阶段是当前阶段的子阶段, 所有阶段都记录在同一个指标中,
+// 通过 span (阶段名) 和 parent (父阶段的完整路径, 根阶段为空字符串) 标签区分,
+// 因此 parent="" 的即为根阶段(整个流程)的总耗时.
+// 为保证所有阶段的标签名一致, 不使用 ctx 上的标签, 只使用 WithLabels 设置的默认标签及传入的 kvs,
+// 各阶段传入的 kvs 需要使用相同的标签名.
+//
+//	ctx, end := c.StartSpan(ctx, "request")
+//	defer end()
+//	ctx2, end2 := c.StartSpan(ctx, "db")
+//	// ...
+//	end2()
+//
+//	// namespace:subsystem:timer:span_seconds_bucket{parent="",span="request"}
+//	// namespace:subsystem:timer:span_seconds_bucket{parent="request",span="db"}
+func (c *client) StartSpan(ctx context.Context, name string, kvs ...string) (context.Context, func() time.Duration) {
+	start := time.Now()
+	s := &span{path: name}
+	parent := ""
+	if p, ok := ctx.Value(spanKey{}).(*span); ok {
+		parent = p.path
+		s.path = p.path + "/" + name
+	}
+	var once sync.Once
+	var cost time.Duration
+	return context.WithValue(ctx, spanKey{}, s), func() time.Duration {
+		once.Do(func() {
+			cost = time.Since(start)
+			c.emit(ctx, call{kind: KindHistogram, names: timerNames, name: "span", desc: "阶段耗时", value: c.timeValue(cost),
+				kvs: append(kvs[:len(kvs):len(kvs)], "span", name, "parent", parent), noCtxLabels: true})
+		})
+		return cost
+	}
+}
+
+// CtxSpanPath 返回 ctx 上当前阶段的完整路径, 不在阶段中时返回空字符串
+func CtxSpanPath(ctx context.Context) string {
+	if s, ok := ctx.Value(spanKey{}).(*span); ok {
+		return s.path
+	}
+	return ""
+}
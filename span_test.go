@@ -0,0 +1,60 @@
+package monitor_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"code.gopub.tech/commons/assert"
+	"code.gopub.tech/monitor"
+)
+
+func TestSpan(t *testing.T) {
+	c := monitor.NewClient()
+	ctx := monitor.CtxAddLabels(ctx, "k", "v")
+	root, end := c.StartSpan(ctx, "request")
+	assert.True(t, monitor.CtxSpanPath(root) == "request")
+
+	db, endDB := c.StartSpan(root, "db")
+	assert.True(t, monitor.CtxSpanPath(db) == "request/db")
+	// ctx 上的标签不会改变阶段指标的标签名
+	db = monitor.CtxAddLabels(db, "x", "y")
+	_, endQuery := c.StartSpan(db, "query")
+	endQuery()
+	endDB()
+	_, endRender := c.StartSpan(root, "render")
+	endRender()
+	total := end()
+	assert.True(t, total == end())
+	// 不同的根阶段使用相同的指标
+	_, endOther := c.StartSpan(monitor.CtxAddLabels(ctx, "other", "1"), "job")
+	endOther()
+
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	t.Log(body)
+	for _, s := range []string{
+		`timer:span_seconds_count{parent="",span="request"} 1`,
+		`timer:span_seconds_count{parent="request",span="db"} 1`,
+		`timer:span_seconds_count{parent="request/db",span="query"} 1`,
+		`timer:span_seconds_count{parent="request",span="render"} 1`,
+		`timer:span_seconds_count{parent="",span="job"} 1`,
+	} {
+		assert.True(t, strings.Contains(body, s), s)
+	}
+}
+
+func TestSpanLabels(t *testing.T) {
+	c := monitor.NewClient(monitor.WithLabels("app", "a"))
+	ctx, end := c.StartSpan(ctx, "request", "route", "/users")
+	_, endDB := c.StartSpan(ctx, "db", "route", "/users")
+	endDB()
+	end()
+
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	assert.True(t, strings.Contains(body, `timer:span_seconds_count{app="a",parent="",route="/users",span="request"} 1`), body)
+	assert.True(t, strings.Contains(body, `timer:span_seconds_count{app="a",parent="request",route="/users",span="db"} 1`), body)
+}
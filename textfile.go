@@ -0,0 +1,150 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// WithTextfile 每隔 interval 将 registry 以 prometheus 文本格式写入 filename, 调用 Close 时会再写入一次
+// 适用于不能监听端口的进程, 配合 node_exporter 的 textfile collector 使用.
+// 写入时先写临时文件再重命名, 保证读取方不会读到不完整的文件.
+// interval 不是正数时通过 logger 输出错误, 只在 Close 时写入.
+//
+//	c := monitor.NewClient(monitor.WithTextfile("/var/lib/node_exporter/textfile/app.prom", time.Minute))
+//	defer c.Close()
+func WithTextfile(filename string, interval time.Duration) Opt {
+	return func(c *client) {
+		c.starters = append(c.starters, func(c *client) {
+			write := func() error {
+				err := prometheus.WriteToTextfile(filename, c.registry)
+				if err != nil {
+					c.recordErr(filename, "write_textfile")
+					c.logger(context.Background(), "write_textfile|WriteToTextfileFailed", "filename", filename, "err", err)
+				}
+				return err
+			}
+			c.life.addCloser(write)
+			if interval <= 0 {
+				c.logger(context.Background(), "write_textfile|InvalidInterval", "filename", filename, "interval", interval)
+				return // 只在 Close 时写入
+			}
+			c.life.goBackground(func(done <-chan struct{}) {
+				ticker := time.NewTicker(interval)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-done:
+						return
+					case <-ticker.C:
+						write()
+					}
+				}
+			})
+		})
+	}
+}
+
+// WithTextfileCollector 读取 dir 目录下的 *.prom 文件, 合并到 client 的 registry 中暴露
+// 每次抓取时都会重新读取文件.
+func WithTextfileCollector(dir string) Opt {
+	return func(c *client) {
+		c.starters = append(c.starters, func(c *client) {
+			c.register(context.Background(), NewTextfileCollector(dir, c.logger), dir, "", "register_textfile")
+		})
+	}
+}
+
+// NewTextfileCollector 新建一个 prometheus.Collector, 读取 dir 目录下的 *.prom 文件
+// 解析失败的文件会被跳过, 并通过 logger 输出(logger 为 nil 时使用 [slog.WarnContext]).
+func NewTextfileCollector(dir string, logger func(context.Context, string, ...any)) prometheus.Collector {
+	if logger == nil {
+		logger = slog.WarnContext
+	}
+	return &textfileCollector{dir: dir, logger: logger}
+}
+
+type textfileCollector struct {
+	dir    string
+	logger func(context.Context, string, ...any)
+}
+
+// Describe 不输出任何 Desc, 作为 unchecked collector 注册
+func (t *textfileCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (t *textfileCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx := context.Background()
+	files, err := filepath.Glob(filepath.Join(t.dir, "*.prom"))
+	if err != nil {
+		t.logger(ctx, "textfile|GlobFailed", "dir", t.dir, "err", err)
+		return
+	}
+	for _, file := range files {
+		families, err := parseTextfile(file)
+		if err != nil {
+			t.logger(ctx, "textfile|ParseFailed", "file", file, "err", err)
+			continue
+		}
+		for _, mf := range families {
+			for _, m := range mf.GetMetric() {
+				metric, err := constMetric(mf, m)
+				if err != nil {
+					t.logger(ctx, "textfile|ConvertFailed", "file", file, "name", mf.GetName(), "err", err)
+					continue
+				}
+				ch <- metric
+			}
+		}
+	}
+}
+
+func parseTextfile(file string) (map[string]*dto.MetricFamily, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var parser expfmt.TextParser
+	return parser.TextToMetricFamilies(f)
+}
+
+// constMetric 将解析出的指标转换为 prometheus.Metric
+func constMetric(mf *dto.MetricFamily, m *dto.Metric) (prometheus.Metric, error) {
+	var names, values []string
+	for _, l := range m.GetLabel() {
+		names = append(names, l.GetName())
+		values = append(values, l.GetValue())
+	}
+	desc := prometheus.NewDesc(mf.GetName(), mf.GetHelp(), names, nil)
+	switch mf.GetType() {
+	case dto.MetricType_COUNTER:
+		return prometheus.NewConstMetric(desc, prometheus.CounterValue, m.GetCounter().GetValue(), values...)
+	case dto.MetricType_GAUGE:
+		return prometheus.NewConstMetric(desc, prometheus.GaugeValue, m.GetGauge().GetValue(), values...)
+	case dto.MetricType_HISTOGRAM:
+		h := m.GetHistogram()
+		buckets := map[float64]uint64{}
+		for _, b := range h.GetBucket() {
+			if !math.IsInf(b.GetUpperBound(), +1) {
+				buckets[b.GetUpperBound()] = b.GetCumulativeCount()
+			}
+		}
+		return prometheus.NewConstHistogram(desc, h.GetSampleCount(), h.GetSampleSum(), buckets, values...)
+	case dto.MetricType_SUMMARY:
+		s := m.GetSummary()
+		quantiles := map[float64]float64{}
+		for _, q := range s.GetQuantile() {
+			quantiles[q.GetQuantile()] = q.GetValue()
+		}
+		return prometheus.NewConstSummary(desc, s.GetSampleCount(), s.GetSampleSum(), quantiles, values...)
+	default:
+		return prometheus.NewConstMetric(desc, prometheus.UntypedValue, m.GetUntyped().GetValue(), values...)
+	}
+}
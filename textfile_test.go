@@ -0,0 +1,60 @@
+package monitor_test
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"code.gopub.tech/commons/assert"
+	"code.gopub.tech/monitor"
+)
+
+func TestTextfile(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.prom")
+	writer := monitor.NewClient(monitor.WithTextfile(filename, time.Hour))
+	writer.RecordN(ctx, "xxx_throughput", "xxx总量", 3, "k", "v")
+	writer.Histogram(ctx, "xxx_value", "xxx分布", 1.5, []float64{1, 2})
+	writer.Summary(ctx, "xxx_avg", "xxx平均", 2)
+	// Close 时写入文件
+	assert.True(t, writer.Close() == nil)
+
+	content, err := os.ReadFile(filename)
+	assert.True(t, err == nil)
+	assert.True(t, strings.Contains(string(content), `counter:xxx_throughput{k="v"} 3`))
+
+	// 不完整的文件会被跳过
+	os.WriteFile(filepath.Join(dir, "broken.prom"), []byte("broken{"), 0o644)
+
+	reader := monitor.NewClient(monitor.WithTextfileCollector(dir))
+	reader.Store(ctx, "xxx_current_value", "xxx当前值", 1)
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	reader.Handler().ServeHTTP(w, req)
+	body, _ := io.ReadAll(w.Result().Body)
+	t.Logf("%s", body)
+	assert.True(t, strings.Contains(string(body), `counter:xxx_throughput{k="v"} 3`))
+	assert.True(t, strings.Contains(string(body), `histogram:xxx_value_bucket{le="2"} 1`))
+	assert.True(t, strings.Contains(string(body), `summary:xxx_avg_sum 2`))
+	assert.True(t, strings.Contains(string(body), `gauge:xxx_current_value 1`))
+}
+
+func TestTextfileInvalidInterval(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.prom")
+	var logs []string
+	c := monitor.NewClient(
+		monitor.WithTextfile(filename, 0),
+		monitor.WithLogger(func(_ context.Context, msg string, _ ...any) { logs = append(logs, msg) }),
+	)
+	c.Record(ctx, "xxx_throughput", "xxx总量")
+	assert.DeepEqual(t, logs, []string{"write_textfile|InvalidInterval"})
+	// 只在 Close 时写入
+	assert.True(t, c.Close() == nil)
+	content, err := os.ReadFile(filename)
+	assert.True(t, err == nil && strings.Contains(string(content), "counter:xxx_throughput 1"), err)
+}
@@ -0,0 +1,131 @@
+package monitor
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Track 跟踪一个处理中的操作, 返回的函数在操作结束时调用
+//
+// 处理中的操作数及最早开始的操作已经持续的时间在抓取时实时计算(使用 Gauge 指标前缀/后缀),
+// 因此长时间卡住的操作在结束前就能被发现; 操作结束后耗时记录到普通的 Timer 指标中.
+// 同一个 name 的标签名需要保持一致.
+//
+//	done := c.Track(ctx, "export_job", "导出任务")
+//	defer done()
+//
+//	// namespace:subsystem:gauge:export_job_in_flight
+//	// namespace:subsystem:gauge:export_job_oldest_seconds
+//	// namespace:subsystem:timer:export_job_seconds_bucket
+func (c *client) Track(ctx context.Context, name, desc string, kvs ...string) func() time.Duration {
+	start := time.Now()
+	labels := c.tags(ctx, kvs...)
+	t := c.getTracker(ctx, name, desc, labels)
+	untrack := t.add(labels, start)
+	if untrack == nil {
+		fqName := c.buildFQName(name+"_in_flight", c.appends.Gauge)
+		c.recordErr(fqName, "track_labels_mismatch")
+		c.logger(ctx, "track_labels_mismatch", "name", fqName, "help", desc,
+			"wantLabels", slices.Sorted(maps.Keys(labels)), "actual", t.labelNames)
+	}
+	var once sync.Once
+	var cost time.Duration
+	return func() time.Duration {
+		once.Do(func() {
+			cost = time.Since(start)
+			if untrack != nil {
+				untrack()
+			}
+			c.recordHistogram(ctx, name, desc, timerNames, c.timeValue(cost), nil, kvs...)
+		})
+		return cost
+	}
+}
+
+func (c *client) getTracker(ctx context.Context, name, desc string, labels map[string]string) *tracker {
+	fqName := c.buildFQName(name+"_in_flight", c.appends.Gauge)
+	labelNames := slices.Sorted(maps.Keys(labels))
+	t, loaded := c.trackers.LoadOrStore(fqName, &tracker{
+		labelNames: labelNames,
+		inFlight:   prometheus.NewDesc(fqName, desc+"(处理中的数量)", labelNames, c.constLabels),
+		oldest: prometheus.NewDesc(c.buildFQName(name+"_oldest_seconds", c.appends.Gauge),
+			desc+"(最早开始的处理中操作已持续的秒数)", labelNames, c.constLabels),
+		series: map[string]*trackSeries{},
+	})
+	if !loaded {
+		c.register(ctx, t, fqName, desc, "register_tracker")
+	}
+	return t
+}
+
+// tracker 是一个 prometheus.Collector, 抓取时计算处理中的操作数及最早开始的时间
+type tracker struct {
+	labelNames []string
+	inFlight   *prometheus.Desc
+	oldest     *prometheus.Desc
+	mu         sync.Mutex
+	nextID     uint64
+	series     map[string]*trackSeries
+}
+
+type trackSeries struct {
+	labelValues []string
+	ops         map[uint64]time.Time
+}
+
+// add 添加一个操作, 返回移除该操作的函数; 标签名不一致时返回 nil
+func (t *tracker) add(labels map[string]string, start time.Time) func() {
+	if len(labels) != len(t.labelNames) {
+		return nil
+	}
+	values := make([]string, len(t.labelNames))
+	for i, name := range t.labelNames {
+		v, ok := labels[name]
+		if !ok {
+			return nil
+		}
+		values[i] = v
+	}
+	key := strings.Join(values, "\xff")
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.series[key]
+	if !ok {
+		s = &trackSeries{labelValues: values, ops: map[uint64]time.Time{}}
+		t.series[key] = s
+	}
+	t.nextID++
+	id := t.nextID
+	s.ops[id] = start
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(s.ops, id)
+	}
+}
+
+func (t *tracker) Describe(ch chan<- *prometheus.Desc) {
+	ch <- t.inFlight
+	ch <- t.oldest
+}
+
+// Collect 没有处理中的操作时, 输出的值均为 0
+func (t *tracker) Collect(ch chan<- prometheus.Metric) {
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, s := range t.series {
+		var age float64
+		for _, start := range s.ops {
+			age = max(age, now.Sub(start).Seconds())
+		}
+		ch <- prometheus.MustNewConstMetric(t.inFlight, prometheus.GaugeValue, float64(len(s.ops)), s.labelValues...)
+		ch <- prometheus.MustNewConstMetric(t.oldest, prometheus.GaugeValue, age, s.labelValues...)
+	}
+}
@@ -0,0 +1,45 @@
+package monitor_test
+
+import (
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"code.gopub.tech/commons/assert"
+	"code.gopub.tech/monitor"
+)
+
+func TestTrack(t *testing.T) {
+	c := monitor.NewClient()
+	scrape := func() string {
+		w := httptest.NewRecorder()
+		c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		return w.Body.String()
+	}
+
+	done1 := c.Track(ctx, "export_job", "导出任务", "k", "v")
+	time.Sleep(time.Millisecond * 20)
+	done2 := c.Track(ctx, "export_job", "导出任务", "k", "v")
+	// 标签名不一致, 不计入处理中, 但仍然记录耗时
+	c.Track(ctx, "export_job", "导出任务", "x", "y")
+
+	body := scrape()
+	t.Log(body)
+	assert.True(t, strings.Contains(body, `gauge:export_job_in_flight{k="v"} 2`))
+	m := regexp.MustCompile(`gauge:export_job_oldest_seconds\{k="v"\} (\S+)`).FindStringSubmatch(body)
+	assert.True(t, len(m) == 2)
+	age, _ := strconv.ParseFloat(m[1], 64)
+	assert.True(t, age >= 0.02, age)
+
+	assert.True(t, done1() >= time.Millisecond*20)
+	done1() // 重复调用无影响
+	done2()
+	body = scrape()
+	assert.True(t, strings.Contains(body, `gauge:export_job_in_flight{k="v"} 0`))
+	assert.True(t, strings.Contains(body, `gauge:export_job_oldest_seconds{k="v"} 0`))
+	assert.True(t, strings.Contains(body, `timer:export_job_seconds_count{k="v"} 2`))
+	assert.True(t, strings.Contains(body, `counter:internal_monitor_error{kind="track_labels_mismatch",name="gauge:export_job_in_flight"} 1`))
+}
@@ -0,0 +1,136 @@
+package monitor
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"code.gopub.tech/commons/nums"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Unit 打点值的单位, 记录时换算为基本单位, 并在指标名后追加基本单位的后缀
+// 换算方式为 基本单位的值 = 值 * Scale + Offset. 可以自定义, 如 Unit{Suffix: "_meters", Scale: 1000} 表示千米.
+type Unit struct {
+	Suffix  string  // 基本单位的后缀, 如 _bytes
+	Scale   float64 // 一个单位等于多少基本单位
+	Offset  float64
+	Buckets []float64 // HistogramUnit 未指定分布时使用的默认分布, 使用基本单位
+}
+
+// 常用单位
+// 参见 https://prometheus.io/docs/practices/naming/#base-units
+var (
+	Bytes     = Unit{Suffix: "_bytes", Scale: 1, Buckets: byteBuckets}
+	Kibibytes = Unit{Suffix: "_bytes", Scale: 1 << 10, Buckets: byteBuckets}
+	Mebibytes = Unit{Suffix: "_bytes", Scale: 1 << 20, Buckets: byteBuckets}
+	Gibibytes = Unit{Suffix: "_bytes", Scale: 1 << 30, Buckets: byteBuckets}
+
+	Ratio   = Unit{Suffix: "_ratio", Scale: 1, Buckets: ratioBuckets}    // 0~1
+	Percent = Unit{Suffix: "_ratio", Scale: 0.01, Buckets: ratioBuckets} // 0~100, 记录为 0~1
+
+	Celsius    = Unit{Suffix: "_celsius", Scale: 1, Buckets: celsiusBuckets}
+	Fahrenheit = Unit{Suffix: "_celsius", Scale: 5.0 / 9, Offset: -32 * 5.0 / 9, Buckets: celsiusBuckets}
+	Kelvin     = Unit{Suffix: "_celsius", Scale: 1, Offset: -273.15, Buckets: celsiusBuckets}
+)
+
+// 常用单位的默认分布
+var (
+	byteBuckets    = prometheus.ExponentialBuckets(64, 4, 10) // 64B ~ 16MiB
+	ratioBuckets   = []float64{.05, .1, .25, .5, .75, .9, .95, .99, 1}
+	celsiusBuckets = []float64{-20, -10, 0, 10, 20, 30, 40, 50, 60, 80, 100}
+)
+
+// Base 将 value 换算为基本单位
+func (u Unit) Base(value nums.AnyNumber) float64 {
+	return nums.To[float64](value)*u.Scale + u.Offset
+}
+
+// name 在指标名后追加基本单位的后缀, 已经以后缀结尾时不重复追加
+func (u Unit) name(name string) string {
+	if strings.HasSuffix(name, u.Suffix) {
+		return name
+	}
+	return name + u.Suffix
+}
+
+// RecordUnit 按单位累加计数器(使用 Counter 指标前缀/后缀)
+// 累加的是增量, 不能使用 Offset 不为 0 的单位(如 Fahrenheit, Kelvin), 否则通过 logger 输出并丢弃本次打点.
+//
+//	// namespace:subsystem:counter:sent_bytes
+//	c.RecordUnit(ctx, "sent", "发送字节数", n, monitor.Bytes)
+func (c *client) RecordUnit(ctx context.Context, name, desc string, value nums.AnyNumber, unit Unit, kvs ...string) {
+	if unit.Offset != 0 {
+		fqName := c.prometheusOpt(unit.name(name), desc, counterNames).Name
+		c.recordErr(fqName, "record_unit_offset")
+		c.logger(ctx, "record_unit|OffsetNotAllowed", "name", fqName, "help", desc, "unit", unit.Suffix)
+		return
+	}
+	c.RecordN(ctx, unit.name(name), desc, unit.Base(value), kvs...)
+}
+
+// StoreUnit 按单位存储当前瞬时值(使用 Gauge 指标前缀/后缀)
+//
+//	// namespace:subsystem:gauge:heap_bytes 536870912
+//	c.StoreUnit(ctx, "heap", "堆内存", 512, monitor.Mebibytes)
+//	// namespace:subsystem:gauge:cpu_usage_ratio 0.85
+//	c.StoreUnit(ctx, "cpu_usage", "CPU 使用率", 85, monitor.Percent)
+//	// namespace:subsystem:gauge:room_celsius 37
+//	c.StoreUnit(ctx, "room", "室温", 98.6, monitor.Fahrenheit)
+func (c *client) StoreUnit(ctx context.Context, name, desc string, value nums.AnyNumber, unit Unit, kvs ...string) {
+	c.Store(ctx, unit.name(name), desc, unit.Base(value), kvs...)
+}
+
+// HistogramUnit 按单位记录值的分布(使用 Histogram 指标前缀/后缀)
+// buckets 与 value 使用相同的单位, 为空时使用 unit.Buckets (基本单位);
+// 两者都为空时(如自定义的 Unit)通过 logger 输出并丢弃本次打点, 耗时的默认分布对其他单位没有意义.
+//
+//	// namespace:subsystem:histogram:body_bytes_bucket{le="1024"}
+//	c.HistogramUnit(ctx, "body", "请求体大小", 3, monitor.Kibibytes, []float64{1, 64, 1024})
+func (c *client) HistogramUnit(ctx context.Context, name, desc string, value nums.AnyNumber, unit Unit, buckets []float64, kvs ...string) {
+	base := unit.Buckets
+	if len(buckets) > 0 {
+		base = nil
+		for _, b := range buckets {
+			base = append(base, unit.Base(b))
+		}
+	}
+	if len(base) == 0 {
+		fqName := c.prometheusOpt(unit.name(name), desc, histogramNames).Name
+		c.recordErr(fqName, "histogram_unit_buckets")
+		c.logger(ctx, "histogram_unit|MissingBuckets", "name", fqName, "help", desc, "unit", unit.Suffix)
+		return
+	}
+	c.Histogram(ctx, unit.name(name), desc, unit.Base(value), base, kvs...)
+}
+
+// timeUnitSuffixes 支持的耗时单位及 Timer 的默认后缀
+var timeUnitSuffixes = map[time.Duration]string{
+	time.Second:      "_seconds",
+	time.Millisecond: "_ms",
+	time.Microsecond: "_us",
+}
+
+// WithTimeUnit 设置耗时的单位, 支持 time.Second, time.Millisecond, time.Microsecond
+//
+// 默认值是 time.Second. 作用于所有记录耗时的 API (Cost, CostBuckets, Timer, Observe, Do, Track, StartSpan,
+// HTTPMiddleware, RoundTripper 等): 耗时换算为该单位记录; Timer 的后缀(包括 WithNameAppend 设置的)以 _seconds 结尾时随之改为 _ms 或 _us;
+// 未设置默认分布时默认分布也按该单位换算. Timer, HTTPBuckets 等以 float64 指定的分布需要使用该单位.
+// 注意 prometheus 推荐使用秒为单位, 仅用于兼容已有的看板.
+//
+//	c := monitor.NewClient(monitor.WithTimeUnit(time.Millisecond))
+//	// namespace:subsystem:timer:query_ms_bucket{le="5"}
+//	c.Cost(ctx, "query", "查询耗时", cost)
+func WithTimeUnit(unit time.Duration) Opt {
+	return func(c *client) {
+		c.timeUnit = unit
+	}
+}
+
+// timeValue 将耗时换算为 WithTimeUnit 设置的单位
+func (c *client) timeValue(d time.Duration) float64 {
+	if c.timeUnit == time.Second {
+		return d.Seconds()
+	}
+	return float64(d) / float64(c.timeUnit)
+}
@@ -0,0 +1,97 @@
+package monitor_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"code.gopub.tech/commons/assert"
+	"code.gopub.tech/monitor"
+)
+
+func TestUnits(t *testing.T) {
+	assert.True(t, monitor.Mebibytes.Base(2) == 2<<20)
+	assert.True(t, monitor.Percent.Base(85) == 0.85)
+	assert.True(t, monitor.Fahrenheit.Base(212) == 100)
+	assert.True(t, monitor.Kelvin.Base(273.15) == 0)
+
+	c := monitor.NewClient()
+	c.StoreUnit(ctx, "heap", "堆内存", 512, monitor.Mebibytes)
+	c.StoreUnit(ctx, "cpu_usage_ratio", "CPU 使用率", 50, monitor.Percent) // 已经带有后缀
+	c.StoreUnit(ctx, "room", "室温", 32, monitor.Fahrenheit)
+	c.RecordUnit(ctx, "sent", "发送字节数", 3, monitor.Kibibytes)
+	c.HistogramUnit(ctx, "body", "请求体大小", 3, monitor.Kibibytes, []float64{1, 64})
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	t.Log(body)
+	assert.True(t, strings.Contains(body, "\ngauge:heap_bytes 5.36870912e+08\n"))
+	assert.True(t, strings.Contains(body, "\ngauge:cpu_usage_ratio 0.5\n"))
+	assert.True(t, strings.Contains(body, "\ngauge:room_celsius 0\n"))
+	assert.True(t, strings.Contains(body, "\ncounter:sent_bytes 3072\n"))
+	assert.True(t, strings.Contains(body, `histogram:body_bytes_bucket{le="1024"} 0`))
+	assert.True(t, strings.Contains(body, `histogram:body_bytes_bucket{le="65536"} 1`))
+}
+
+func TestUnitsRejected(t *testing.T) {
+	var logs []string
+	c := monitor.NewClient(monitor.WithLogger(func(_ context.Context, msg string, _ ...any) { logs = append(logs, msg) }))
+	c.RecordUnit(ctx, "heat", "热量", 10, monitor.Kelvin) // 计数器累加增量, 不能加上 Offset
+	c.HistogramUnit(ctx, "distance", "距离", 3, monitor.Unit{Suffix: "_meters", Scale: 1000}, nil)
+	c.HistogramUnit(ctx, "usage", "使用率", 50, monitor.Percent, nil) // 使用单位的默认分布
+	assert.DeepEqual(t, logs, []string{"record_unit|OffsetNotAllowed", "histogram_unit|MissingBuckets"})
+
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	t.Log(body)
+	assert.True(t, !strings.Contains(body, "\ncounter:heat_celsius "))
+	assert.True(t, !strings.Contains(body, "histogram:distance_meters_bucket"))
+	assert.True(t, strings.Contains(body, `histogram:usage_ratio_bucket{le="0.5"} 1`))
+	assert.True(t, strings.Contains(body, `histogram:usage_ratio_bucket{le="0.25"} 0`))
+	assert.True(t, strings.Contains(body, `internal_monitor_error{kind="record_unit_offset",name="counter:heat_celsius"} 1`))
+	assert.True(t, strings.Contains(body, `internal_monitor_error{kind="histogram_unit_buckets",name="histogram:distance_meters"} 1`))
+}
+
+func TestWithTimeUnit(t *testing.T) {
+	c := monitor.NewClient(monitor.WithTimeUnit(time.Millisecond))
+	c.Cost(ctx, "query", "查询耗时", 20*time.Millisecond)
+	c.CostBuckets(ctx, "load", "加载耗时", 3*time.Millisecond, []time.Duration{time.Millisecond, 10 * time.Millisecond})
+	c.Timer(1, 1000)(ctx, "job", "任务耗时")
+	c.Observe()(ctx, "step", "步骤耗时")
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	t.Log(body)
+	assert.True(t, strings.Contains(body, `timer:query_ms_bucket{le="25"} 1`)) // 默认分布按毫秒换算
+	assert.True(t, strings.Contains(body, `timer:query_ms_bucket{le="10"} 0`))
+	assert.True(t, strings.Contains(body, "\ntimer:query_ms_sum 20\n"))
+	assert.True(t, strings.Contains(body, `timer:load_ms_bucket{le="1"} 0`))
+	assert.True(t, strings.Contains(body, `timer:load_ms_bucket{le="10"} 1`))
+	assert.True(t, strings.Contains(body, `timer:job_ms_bucket{le="1000"} 1`))
+	assert.True(t, strings.Contains(body, "\ntimer:step_ms_count 1\n"))
+
+	// 不支持的单位使用秒
+	var logs []string
+	c = monitor.NewClient(
+		monitor.WithTimeUnit(time.Minute),
+		monitor.WithLogger(func(_ context.Context, msg string, _ ...any) { logs = append(logs, msg) }),
+	)
+	c.Cost(ctx, "query", "查询耗时", 2*time.Second)
+	assert.DeepEqual(t, logs, []string{"invalid_time_unit"})
+	w = httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.True(t, strings.Contains(w.Body.String(), "\ntimer:query_seconds_sum 2\n"), w.Body.String())
+
+	// 自定义的 _seconds 后缀也随之修改
+	c = monitor.NewClient(
+		monitor.WithTimeUnit(time.Millisecond),
+		monitor.WithNameAppend(monitor.NameAppends{Timer: monitor.NameAppend{Prefix: "t:", Suffix: "_duration_seconds"}}),
+	)
+	c.Cost(ctx, "query", "查询耗时", 20*time.Millisecond)
+	w = httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.True(t, strings.Contains(w.Body.String(), "\nt:query_duration_ms_sum 20\n"), w.Body.String())
+}
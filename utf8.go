@@ -0,0 +1,174 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"code.gopub.tech/commons/choose"
+	"github.com/prometheus/common/model"
+)
+
+// NameEscaping 不支持 UTF-8 指标名的抓取方使用的转义方式, 参见 WithUTF8Names
+type NameEscaping string
+
+const (
+	EscapingUnderscores NameEscaping = model.EscapeUnderscores // 不合法的字符替换为下划线, 如 总量 => _
+	EscapingDots        NameEscaping = model.EscapeDots        // 同上, 另外 . 转义为 _dot_, _ 转义为 __
+	EscapingValues      NameEscaping = model.EscapeValues      // U__ 开头, 不合法的字符转义为 _unicode_ 编码, 与 EscapeName 相同, 默认值
+)
+
+// EnableUTF8Names 将 prometheus 全局的指标名校验方式 model.NameValidationScheme 设置为 model.UTF8Validation,
+// 使用 WithUTF8Names 之前需要调用.
+//
+// 该设置是进程全局的且没有加锁, 需要在 main 或 init 中, 创建 client 及注册任何指标之前调用一次.
+// 设置后所有 registry 都接受 UTF-8 指标名及标签名; 原有的指标名都是合法的 UTF-8 指标名, 因此不影响已有的指标.
+//
+//	func init() {
+//		monitor.EnableUTF8Names()
+//	}
+func EnableUTF8Names() {
+	model.NameValidationScheme = model.UTF8Validation
+}
+
+// WithUTF8Names 指标名及标签名使用 UTF-8 原样输出, 不再通过 EscapeName 转义(如 总量 不再转义为 U___603b__91cf_)
+//
+// 需要先调用 EnableUTF8Names, 否则通过 logger 输出错误并忽略该选项.
+// Handler 按抓取请求的 Accept 头协商: Prometheus 3 等支持 UTF-8 的抓取方(escaping=allow-utf-8)得到原样的指标名,
+// 其他抓取方得到按 fallback 转义后的指标名, fallback 为空时使用 EscapingValues.
+// 转义后与其他指标名相同的指标(如 EscapingUnderscores 时 总量 与 数量 都转义为 _)会被丢弃,
+// 并记录到内部异常指标中(kind 为 name_collision); 同一指标的标签名转义后相同时同样丢弃(kind 为 label_collision).
+//
+//	monitor.EnableUTF8Names()
+//	c := monitor.NewClient(monitor.WithUTF8Names(monitor.EscapingValues))
+//	c.Record(ctx, "总量", "总量") // counter:总量
+func WithUTF8Names(fallback NameEscaping) Opt {
+	return func(c *client) {
+		c.utf8Names = true
+		c.escaping = fallback
+	}
+}
+
+// setupUTF8Names 校验 WithUTF8Names 的配置, 未调用 EnableUTF8Names 时不开启
+func (c *client) setupUTF8Names() {
+	logger := choose.If(c.logger != nil, c.logger, slog.WarnContext)
+	if model.NameValidationScheme != model.UTF8Validation {
+		logger(context.Background(), "utf8_names|NotEnabled")
+		c.utf8Names = false
+		return
+	}
+	if c.escaping == "" {
+		c.escaping = EscapingValues
+	}
+	if _, err := model.ToEscapingScheme(string(c.escaping)); err != nil || c.escaping == model.AllowUTF8 {
+		logger(context.Background(), "utf8_names|InvalidEscaping", "escaping", c.escaping)
+		c.escaping = EscapingValues
+	}
+}
+
+// escapeName 开启 UTF-8 指标名时原样返回合法的 UTF-8 字符串, 否则使用 EscapeName 转义
+func (c *client) escapeName(s string) string {
+	if c.utf8Names && utf8.ValidString(s) {
+		return s
+	}
+	return EscapeName(s)
+}
+
+// negotiateEscaping 抓取请求的 Accept 头没有指定 escaping 参数时, 使用 fallback 转义指标名
+func negotiateEscaping(h http.Handler, fallback NameEscaping) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept := r.Header.Get("Accept")
+		if !strings.Contains(accept, model.EscapingKey+"=") {
+			items := splitList(accept)
+			if len(items) == 0 {
+				items = []string{"text/plain"}
+			}
+			for i := range items {
+				items[i] += ";" + model.EscapingKey + "=" + string(fallback)
+			}
+			r = r.Clone(r.Context())
+			r.Header.Set("Accept", strings.Join(items, ","))
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// checkEscaping 开启 UTF-8 指标名时, 检查指标名按 fallback 转义后是否与其他指标名相同,
+// 以及同一指标的标签名转义后是否相同, 返回 false 表示丢弃本次打点
+func (c *client) checkEscaping(ctx context.Context, e Event) bool {
+	if !c.utf8Names {
+		return true
+	}
+	escaped := c.fallbackName(e.Name)
+	if name, _ := c.escaped.LoadOrStore(escaped, e.Name); name != e.Name {
+		c.recordErr(e.Name, "name_collision")
+		c.logger(ctx, "name_collision", "name", e.Name, "help", e.Help, "escaped", escaped, "other", name)
+		return false
+	}
+	if len(e.ConstLabels)+len(e.Labels) < 2 {
+		return true
+	}
+	labels := map[string]string{} // 转义后的标签名 => 标签名
+	for _, m := range []map[string]string{e.ConstLabels, e.Labels} {
+		for label := range m {
+			escaped := c.fallbackName(label)
+			if other, ok := labels[escaped]; ok && other != label {
+				// 如 EscapingUnderscores 时 区域 与 地区 都转义为 __
+				c.recordErr(e.Name, "label_collision")
+				c.logger(ctx, "label_collision", "name", e.Name, "help", e.Help,
+					"label", label, "escaped", escaped, "other", other)
+				return false
+			}
+			labels[escaped] = label
+		}
+	}
+	return true
+}
+
+// fallbackName 返回指标名/标签名按 fallback 转义后的名称, 结果会被缓存
+func (c *client) fallbackName(name string) string {
+	if escaped, ok := c.escapes.Load(name); ok {
+		return escaped
+	}
+	scheme, _ := model.ToEscapingScheme(string(c.escaping))
+	escaped := model.EscapeName(name, scheme)
+	c.escapes.Store(name, escaped)
+	return escaped
+}
+
+// checkLabel 校验标签名及标签值
+// 标签名不能为空, 不能以 __ 开头(保留给 prometheus 内部使用), 开启 UTF-8 指标名时可以是任意 UTF-8 字符串,
+// 否则只能使用 [a-zA-Z_][a-zA-Z0-9_]*; 标签值需要是合法的 UTF-8 字符串.
+func checkLabel(name, value string, utf8Names bool) error {
+	switch {
+	case name == "":
+		return errors.New("empty label name")
+	case strings.HasPrefix(name, "__"):
+		return fmt.Errorf("invalid label name %q, the __ prefix is reserved", name)
+	case utf8Names && !utf8.ValidString(name):
+		return fmt.Errorf("invalid label name %q, not valid UTF-8", name)
+	case !utf8Names && !model.LabelName(name).IsValidLegacy():
+		return fmt.Errorf("invalid label name %q, want [a-zA-Z_][a-zA-Z0-9_]*", name)
+	case !utf8.ValidString(value):
+		return fmt.Errorf("value of label %q is not valid UTF-8", name)
+	}
+	return nil
+}
+
+// checkLabels 校验打点事件的常量标签及标签, 返回 false 表示丢弃本次打点
+func (c *client) checkLabels(ctx context.Context, e Event) bool {
+	for _, labels := range []map[string]string{e.ConstLabels, e.Labels} {
+		for name, value := range labels {
+			if err := checkLabel(name, value, c.utf8Names); err != nil {
+				c.recordErr(e.Name, "invalid_label")
+				c.logger(ctx, "invalid_label", "name", e.Name, "help", e.Help, "err", err)
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,169 @@
+package monitor_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"code.gopub.tech/commons/assert"
+	"code.gopub.tech/monitor"
+	"github.com/prometheus/common/model"
+)
+
+// enableUTF8Names 开启 UTF-8 指标名, 测试结束时恢复
+func enableUTF8Names(t *testing.T) {
+	scheme := model.NameValidationScheme
+	t.Cleanup(func() { model.NameValidationScheme = scheme })
+	monitor.EnableUTF8Names()
+}
+
+func TestUTF8Names(t *testing.T) {
+	enableUTF8Names(t)
+	scrape := func(escaping monitor.NameEscaping, accept string) string {
+		c := monitor.NewClient(monitor.WithUTF8Names(escaping), monitor.WithNamespace("应用"))
+		c.Record(ctx, "总量", "总量", "区域", "华北")
+		c.Sub("db").Store(ctx, "conn.count", "连接数", 1)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/metrics", nil)
+		r.Header.Set("Accept", accept)
+		c.Handler().ServeHTTP(w, r)
+		t.Log(w.Body.String())
+		return w.Body.String()
+	}
+
+	body := scrape(monitor.EscapingUnderscores, "")
+	assert.True(t, strings.Contains(body, "\n__:counter:__{__=\"华北\"} 1\n"))
+	assert.True(t, strings.Contains(body, "\n__:db:gauge:conn_count 1\n"))
+
+	body = scrape(monitor.EscapingDots, "text/plain;version=0.0.4")
+	assert.True(t, strings.Contains(body, "\n__:db:gauge:conn_dot_count 1\n"))
+
+	// 默认使用 EscapingValues
+	body = scrape("", "")
+	assert.True(t, strings.Contains(body, "\nU___5e94__7528_:counter:_603b__91cf_{U___533a__57df_=\"华北\"} 1\n"))
+
+	// 支持 UTF-8 的抓取方得到原样的指标名
+	body = scrape(monitor.EscapingValues, "text/plain;version=0.0.4;escaping=allow-utf-8")
+	assert.True(t, strings.Contains(body, "\n{\"应用:counter:总量\",\"区域\"=\"华北\"} 1\n"))
+	assert.True(t, strings.Contains(body, "\n{\"应用:db:gauge:conn.count\"} 1\n"))
+
+	// 未开启时仍然转义
+	c := monitor.NewClient(monitor.WithNamespace("应用"))
+	c.Record(ctx, "总量", "总量")
+	assert.True(t, c.Catalog()[0].Name == "U___5e94__7528_:counter:U___603b__91cf_", c.Catalog())
+}
+
+func TestInvalidLabels(t *testing.T) {
+	var logs []string
+	c := monitor.NewClient(monitor.WithLogger(func(_ context.Context, msg string, args ...any) {
+		logs = append(logs, msg)
+	}))
+	c.Record(ctx, "req", "请求数", "区域", "华北")
+	c.Record(ctx, "req", "请求数", "__name", "x")
+	c.Record(ctx, "req", "请求数", "zone", "\xff")
+	c.Record(ctx, "req", "请求数", "zone", "north")
+	assert.DeepEqual(t, logs, []string{"invalid_label", "invalid_label", "invalid_label"})
+
+	enableUTF8Names(t)
+	c = monitor.NewClient(monitor.WithUTF8Names(monitor.EscapingUnderscores))
+	c.Record(ctx, "req", "请求数", "区域", "华北")
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/metrics", nil)
+	r.Header.Set("Accept", "text/plain;version=0.0.4;escaping=allow-utf-8")
+	c.Handler().ServeHTTP(w, r)
+	assert.True(t, strings.Contains(w.Body.String(), "\ncounter:req{\"区域\"=\"华北\"} 1\n"), w.Body.String())
+
+	_, err := monitor.LoadConfig(strings.NewReader("utf8_names: true\nlabels: {区域: 华北}\nname_escaping: dots\n"))
+	assert.True(t, err == nil, err)
+	_, err = monitor.LoadConfig(strings.NewReader("labels: {区域: 华北}\n"))
+	assert.True(t, err != nil && strings.Contains(err.Error(), `invalid label name "区域"`), err)
+	_, err = monitor.LoadConfig(strings.NewReader("utf8_names: true\nname_escaping: hex\n"))
+	assert.True(t, err != nil && strings.Contains(err.Error(), "name_escaping"), err)
+}
+
+func TestUTF8NameCollision(t *testing.T) {
+	enableUTF8Names(t)
+	var logs []string
+	c := monitor.NewClient(
+		monitor.WithUTF8Names(monitor.EscapingUnderscores),
+		monitor.WithLogger(func(_ context.Context, msg string, _ ...any) { logs = append(logs, msg) }),
+	)
+	c.Record(ctx, "总量", "总量")
+	c.Record(ctx, "数量", "数量") // 都转义为 counter:__
+	c.Record(ctx, "总量", "总量")
+	assert.DeepEqual(t, logs, []string{"name_collision"})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/metrics", nil)
+	r.Header.Set("Accept", "text/plain;version=0.0.4;escaping=allow-utf-8")
+	c.Handler().ServeHTTP(w, r)
+	body := w.Body.String()
+	assert.True(t, strings.Contains(body, "\n{\"counter:总量\"} 2\n"), body)
+	assert.True(t, !strings.Contains(body, "{\"counter:数量\""), body)
+	assert.True(t, strings.Contains(body, `counter:internal_monitor_error{kind="name_collision",name="counter:数量"} 1`), body)
+
+	// 默认的 EscapingValues 不会冲突
+	c = monitor.NewClient(monitor.WithUTF8Names(""))
+	c.Record(ctx, "总量", "总量")
+	c.Record(ctx, "数量", "数量")
+	assert.True(t, len(c.Catalog()) == 2, c.Catalog())
+}
+
+func TestUTF8NamesNotEnabled(t *testing.T) {
+	defer func(scheme model.ValidationScheme) { model.NameValidationScheme = scheme }(model.NameValidationScheme)
+	model.NameValidationScheme = model.LegacyValidation
+	var logs []string
+	c := monitor.NewClient(
+		monitor.WithUTF8Names(""),
+		monitor.WithLogger(func(_ context.Context, msg string, _ ...any) { logs = append(logs, msg) }),
+	)
+	assert.DeepEqual(t, logs, []string{"utf8_names|NotEnabled"})
+	c.Record(ctx, "总量", "总量")
+	assert.True(t, c.Catalog()[0].Name == "counter:U___603b__91cf_", c.Catalog())
+}
+
+func TestUTF8NamesLintFix(t *testing.T) {
+	enableUTF8Names(t)
+	c := monitor.NewClient(monitor.WithUTF8Names(""),
+		monitor.WithNameLint(monitor.LintFix(), monitor.LintHandler(func(context.Context, monitor.NameLint) {})))
+	c.Record(ctx, "总量", "总量")
+	c.Record(ctx, "数量", "数量")
+	var names []string
+	for _, m := range c.Catalog() {
+		names = append(names, m.Name)
+	}
+	// 不转换为 snake_case, 两个指标名不会被修正为同一个 counter_total
+	assert.DeepEqual(t, names, []string{"counter_总量_total", "counter_数量_total"})
+}
+
+func TestUTF8LabelCollision(t *testing.T) {
+	enableUTF8Names(t)
+	var logs []string
+	c := monitor.NewClient(
+		monitor.WithUTF8Names(monitor.EscapingUnderscores),
+		monitor.WithLogger(func(_ context.Context, msg string, _ ...any) { logs = append(logs, msg) }),
+	)
+	c.Record(ctx, "req", "请求数", "区域", "华北", "地区", "北京") // 都转义为 __
+	c.Record(ctx, "conn", "连接数", "区域", "华北", "zone", "north")
+	assert.DeepEqual(t, logs, []string{"label_collision"})
+
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	t.Log(body)
+	assert.True(t, !strings.Contains(body, "\ncounter:req{"), body)
+	assert.True(t, strings.Contains(body, `counter:conn{zone="north",__="华北"} 1`), body)
+	assert.True(t, strings.Contains(body, `counter:internal_monitor_error{kind="label_collision",name="counter:req"} 1`), body)
+}
+
+func TestUTF8NamesReconfigure(t *testing.T) {
+	enableUTF8Names(t)
+	c := monitor.NewClient(monitor.WithUTF8Names(monitor.EscapingUnderscores))
+	_, err := c.Reconfigure(monitor.WithUTF8Names(monitor.EscapingDots))
+	assert.True(t, err != nil, err)
+	_, err = monitor.NewClient().Reconfigure(monitor.WithUTF8Names(monitor.EscapingUnderscores))
+	assert.True(t, err != nil, err)
+	_, err = c.Reconfigure(monitor.WithBuckets([]float64{1, 2}))
+	assert.True(t, err == nil, err)
+}